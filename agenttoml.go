@@ -0,0 +1,218 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// --- Vibe agent files ---
+
+// vibeAgent is the content of a Vibe agent file (agents/<slug>.toml).
+type vibeAgent struct {
+	Comments       []string // header comment lines, without "# "
+	DisplayName    string
+	Description    string
+	Safety         string
+	AutoApprove    bool
+	SystemPromptID string
+	EnabledTools   []string
+}
+
+// toml serializes the agent. Keys are always written in the same order and
+// every string is a TOML basic string, so any text (quotes, backslashes,
+// control characters, emoji) round-trips through parseTOML unchanged.
+func (v vibeAgent) toml() string {
+	var b strings.Builder
+	w := func(f string, a ...any) { fmt.Fprintf(&b, f, a...) }
+
+	for _, c := range v.Comments {
+		w("# %s\n", commentLine(c))
+	}
+	if len(v.Comments) > 0 {
+		w("\n")
+	}
+	w("display_name = %s\n", tomlString(v.DisplayName))
+	w("description = %s\n", tomlString(v.Description))
+	w("safety = %s\n", tomlString(v.Safety))
+	w("auto_approve = %t\n", v.AutoApprove)
+	w("system_prompt_id = %s\n", tomlString(v.SystemPromptID))
+	tools := make([]string, len(v.EnabledTools))
+	for i, t := range v.EnabledTools {
+		tools[i] = tomlString(t)
+	}
+	w("\nenabled_tools = [%s]\n", strings.Join(tools, ", "))
+	return b.String()
+}
+
+// commentLine keeps a header comment on one line, without the control
+// characters TOML forbids in comments.
+func commentLine(s string) string {
+	s = strings.ReplaceAll(strings.ToValidUTF8(s, "\uFFFD"), "\r\n", " ")
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\r':
+			return ' '
+		case r == '\t':
+			return r
+		case r < 0x20 || r == 0x7f:
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// tomlString quotes s as a TOML basic string. Unlike %q it only uses escapes
+// TOML defines, and replaces invalid UTF-8 with U+FFFD.
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range strings.ToValidUTF8(s, "\uFFFD") {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// agentTOMLKeys are the keys of a Vibe agent file and the TOML type of each.
+var agentTOMLKeys = map[string]string{
+	"display_name":     "string",
+	"description":      "string",
+	"safety":           "string",
+	"auto_approve":     "boolean",
+	"system_prompt_id": "string",
+	"enabled_tools":    "array",
+}
+
+var requiredAgentKeys = []string{"display_name", "description", "safety", "system_prompt_id", "enabled_tools"}
+
+// vibeTools are the built-in Vibe tools an agent can enable.
+var vibeTools = map[string]bool{
+	"read_file": true, "write_file": true, "search_replace": true,
+	"grep": true, "list_dir": true, "bash": true,
+	"ask_user_question": true, "task": true, "todo": true,
+}
+
+// checkAgentTOML validates the agent file rel (relative to vibe-home) and
+// returns its parsed content, or nil if it is not valid TOML.
+func checkAgentTOML(rel, src string, report *conversionReport) map[string]any {
+	doc, err := parseTOML(src)
+	if err != nil {
+		line, msg := 0, err.Error()
+		var te *tomlError
+		if errors.As(err, &te) {
+			line, msg = te.Line, te.Msg
+		}
+		report.errAt(diagInvalidTOML, rel, line, "%s", msg)
+		return nil
+	}
+	// line is the line assigning key, if any.
+	line := func(key string) int { return tomlKeyLine(src, key) }
+
+	for _, k := range requiredAgentKeys {
+		if _, ok := doc[k]; !ok {
+			code := diagMissingField
+			if k == "system_prompt_id" {
+				code = diagMissingPromptID
+			}
+			report.err(code, rel, "missing field %q", k)
+		}
+	}
+
+	var unknown []string
+	for k := range doc {
+		if agentTOMLKeys[k] == "" {
+			unknown = append(unknown, k)
+		}
+	}
+	sort.Strings(unknown)
+	for _, k := range unknown {
+		report.warnAt(diagUnknownKey, rel, line(k), "unknown key %q", k)
+	}
+
+	for _, k := range sortedKeys(agentTOMLKeys) {
+		v, ok := doc[k]
+		if !ok {
+			continue
+		}
+		if got := tomlTypeName(v); got != agentTOMLKeys[k] {
+			report.errAt(diagInvalidField, rel, line(k), "%s: want type %s, got %s", k, agentTOMLKeys[k], got)
+			delete(doc, k)
+		}
+	}
+
+	if s, ok := doc["safety"].(string); ok && !validSafety[s] {
+		report.errAt(diagInvalidSafety, rel, line("safety"), "invalid safety %q (want safe, neutral, destructive or yolo)", s)
+	}
+	if tools, ok := doc["enabled_tools"].([]any); ok {
+		for i, t := range tools {
+			name, ok := t.(string)
+			switch {
+			case !ok:
+				report.errAt(diagInvalidField, rel, line("enabled_tools"), "enabled_tools[%d]: want type string, got %s", i, tomlTypeName(t))
+			case !vibeTools[name]:
+				report.warnAt(diagUnknownTool, rel, line("enabled_tools"), "enabled_tools: unknown tool %q", name)
+			}
+		}
+	}
+	return doc
+}
+
+// tomlTypeName names the TOML type of a decoded value.
+func tomlTypeName(v any) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int64:
+		return "integer"
+	case float64:
+		return "float"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "table"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// tomlKeyLine returns the line of the first top-level assignment of key in
+// src, or 0 if there is none (or key is empty).
+func tomlKeyLine(src, key string) int {
+	if key == "" {
+		return 0
+	}
+	for i, line := range strings.Split(src, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			return 0
+		}
+		if rest, ok := strings.CutPrefix(line, key); ok && strings.HasPrefix(strings.TrimSpace(rest), "=") {
+			return i + 1
+		}
+	}
+	return 0
+}
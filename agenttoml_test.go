@@ -0,0 +1,140 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTOMLStringRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string // parsed value, when it differs from in
+	}{
+		{"empty", "", ""},
+		{"plain", "Product Manager", ""},
+		{"double quotes", `say "hello"`, ""},
+		{"backslashes", `C:\path\to\file \n not a newline`, ""},
+		{"trailing backslash", `ends with \`, ""},
+		{"control characters", "bell\a nul\x00 esc\x1b del\x7f", ""},
+		{"tab and form feed", "a\tb\fc\bd", ""},
+		{"newlines", "line 1\nline 2", ""},
+		{"crlf", "line 1\r\nline 2\r\n", ""},
+		{"triple single quotes", "'''not a literal'''", ""},
+		{"triple double quotes", `"""not multiline"""`, ""},
+		{"comment marker", "# not a comment", ""},
+		{"non-BMP runes", "🧭 Coach 𝕏 \U0001F600", ""},
+		{"invalid utf-8", "bad \xff byte", "bad \uFFFD byte"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tt.want
+			if want == "" {
+				want = tt.in
+			}
+			doc, err := parseTOML("v = " + tomlString(tt.in) + "\n")
+			if err != nil {
+				t.Fatalf("parseTOML(%s): %v", tomlString(tt.in), err)
+			}
+			if got := doc["v"]; got != want {
+				t.Errorf("round trip = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestVibeAgentTOMLRoundTrip(t *testing.T) {
+	hostile := []string{
+		`Agent "Quoted" \ Backslash`,
+		"Multi\nline\r\ndescription\twith\x01controls",
+		"'''literal''' and \"\"\"basic\"\"\"",
+		"emoji 🧭 and non-BMP 𝄞 \U0010FFFF",
+		"# hash = [not, an, array]",
+	}
+	for _, s := range hostile {
+		t.Run(s, func(t *testing.T) {
+			agent := vibeAgent{
+				Comments:       []string{"Auto-generated by bmad2vibe", s},
+				DisplayName:    s,
+				Description:    s,
+				Safety:         "neutral",
+				AutoApprove:    true,
+				SystemPromptID: "bmad-test-agent",
+				EnabledTools:   []string{"read_file", "grep", "ask_user_question"},
+			}
+			src := agent.toml()
+			report := &conversionReport{}
+			doc := checkAgentTOML("agents/bmad-test-agent.toml", src, report)
+			if doc == nil {
+				t.Fatalf("checkAgentTOML rejected:\n%s\nerrors: %v", src, report.errors)
+			}
+			if len(report.errors) > 0 || len(report.warnings) > 0 {
+				t.Errorf("checkAgentTOML: errors %v, warnings %v\n%s", report.errors, report.warnings, src)
+			}
+			for key, want := range map[string]any{
+				"display_name":     s,
+				"description":      s,
+				"safety":           "neutral",
+				"auto_approve":     true,
+				"system_prompt_id": "bmad-test-agent",
+			} {
+				if doc[key] != want {
+					t.Errorf("%s = %q, want %q", key, doc[key], want)
+				}
+			}
+			tools, _ := doc["enabled_tools"].([]any)
+			if len(tools) != 3 || tools[0] != "read_file" || tools[2] != "ask_user_question" {
+				t.Errorf("enabled_tools = %v", tools)
+			}
+			for _, line := range strings.Split(src, "\n")[:2] {
+				if !strings.HasPrefix(line, "# ") {
+					t.Errorf("comment spans lines: %q", line)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckAgentTOMLReportsProblems(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		code string
+		line int
+	}{
+		{"syntax", "display_name = \"unterminated\n", "BV007", 1},
+		{"missing prompt id", `display_name = "A"
+description = "B"
+safety = "safe"
+enabled_tools = []
+`, "BV002", 0},
+		{"bad safety", `display_name = "A"
+description = "B"
+safety = "reckless"
+system_prompt_id = "a"
+enabled_tools = []
+`, "BV004", 3},
+		{"wrong type", `display_name = "A"
+description = "B"
+safety = "safe"
+auto_approve = "yes"
+system_prompt_id = "a"
+enabled_tools = []
+`, "BV008", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := &conversionReport{}
+			checkAgentTOML("agents/a.toml", tt.src, report)
+			for _, d := range report.errors {
+				if d.Code == tt.code {
+					if d.Line != tt.line {
+						t.Errorf("%s on line %d, want %d", tt.code, d.Line, tt.line)
+					}
+					return
+				}
+			}
+			t.Errorf("no %s error, got %v", tt.code, report.errors)
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// --- Agent compilation (BMAD-METHOD source) ---
+//
+// bmad-bundles ships agents pre-compiled to XML. Modules that only exist in
+// BMAD-METHOD (or a fork of it) have src/<module>/agents/*.agent.yaml
+// instead; compileAgentYAML renders those into the same XML structure the
+// BMAD installer produces, so both kinds go through parseAgentBundle.
+
+// agentSource is one agent definition to convert.
+type agentSource struct {
+	Slug     string
+	Path     string
+	Compiled bool // from *.agent.yaml rather than a bundle
+}
+
+// agentSources lists the agents of a module: bundle XML files first, then
+// agent.yaml sources for slugs the bundles lack.
+func agentSources(module, bundlesDir, methodDir string) []agentSource {
+	var out []agentSource
+	seen := make(map[string]bool)
+
+	agentsDir := filepath.Join(bundlesDir, module, "agents")
+	entries, _ := os.ReadDir(agentsDir)
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".xml") {
+			continue
+		}
+		slug := strings.TrimSuffix(e.Name(), ".xml")
+		seen[slug] = true
+		out = append(out, agentSource{Slug: slug, Path: filepath.Join(agentsDir, e.Name())})
+	}
+
+	var compiled []agentSource
+	srcDir := filepath.Join(methodDir, "src", module, "agents")
+	filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !strings.HasSuffix(info.Name(), ".agent.yaml") {
+			return nil
+		}
+		slug := strings.TrimSuffix(info.Name(), ".agent.yaml")
+		if !seen[slug] {
+			seen[slug] = true
+			compiled = append(compiled, agentSource{Slug: slug, Path: path, Compiled: true})
+		}
+		return nil
+	})
+	sort.Slice(compiled, func(i, j int) bool { return compiled[i].Slug < compiled[j].Slug })
+	return append(out, compiled...)
+}
+
+// Escapers for element content and double-quoted attributes. Unlike
+// xml.EscapeText they keep line breaks readable in the generated prompt.
+var (
+	xmlTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	xmlAttrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+)
+
+// menuItemAttrs are the agent.yaml menu keys that become <item> attributes,
+// in output order.
+var menuItemAttrs = []struct{ yaml, attr string }{
+	{"workflow", "workflow"},
+	{"exec", "exec"},
+	{"tmpl", "tmpl"},
+	{"data", "data"},
+	{"action", "action"},
+	{"validate-workflow", "validate-workflow"},
+}
+
+// compileAgentYAML renders an agent.yaml definition as bundle XML.
+func compileAgentYAML(raw []byte, module, slug string) (string, error) {
+	doc, err := parseYAML(string(raw))
+	if err != nil {
+		return "", err
+	}
+	agent := yamlMap(yamlMap(doc)["agent"])
+	if agent == nil {
+		return "", fmt.Errorf("no top-level agent mapping")
+	}
+	meta := yamlMap(agent["metadata"])
+	persona := yamlMap(agent["persona"])
+	if persona == nil {
+		return "", fmt.Errorf("agent has no persona")
+	}
+
+	var b strings.Builder
+	w := func(f string, a ...any) { fmt.Fprintf(&b, f, a...) }
+	esc := func(s string) string { return xmlTextEscaper.Replace(strings.TrimSpace(s)) }
+	attr := func(s string) string { return xmlAttrEscaper.Replace(strings.TrimSpace(s)) }
+
+	id := yamlString(meta["id"])
+	if id == "" {
+		id = fmt.Sprintf("_bmad/%s/agents/%s.md", module, slug)
+	}
+	w("<agent id=\"%s\" name=\"%s\" title=\"%s\" icon=\"%s\"",
+		attr(id), attr(yamlString(meta["name"])), attr(yamlString(meta["title"])), attr(yamlString(meta["icon"])))
+	if d := yamlString(meta["description"]); d != "" {
+		w(" description=\"%s\"", attr(d))
+	}
+	w(">\n")
+
+	menu := yamlList(agent["menu"])
+	handlers := make(map[string]bool)
+	for _, it := range menu {
+		for _, a := range menuItemAttrs {
+			if yamlString(yamlMap(it)[a.yaml]) != "" {
+				handlers[a.attr] = true
+			}
+		}
+	}
+
+	// Activation, as generated by the BMAD installer.
+	steps := []string{
+		"Load persona from this current agent file (already in context)",
+		fmt.Sprintf("🚨 IMMEDIATE ACTION REQUIRED - BEFORE ANY OUTPUT: Load and read {project-root}/_bmad/%s/config.yaml NOW. Store ALL fields as session variables: {user_name}, {communication_language}, {output_folder}. VERIFY: If config not loaded, STOP and report error to user. DO NOT PROCEED to next step until config is successfully loaded and variables stored", module),
+		"Remember: user's name is {user_name}",
+	}
+	steps = append(steps, yamlStrings(agent["critical_actions"])...)
+	steps = append(steps,
+		"Show greeting using {user_name} from config, communicate in {communication_language}, then display numbered list of ALL menu items from menu section",
+		"STOP and WAIT for user input - do NOT execute menu items automatically - accept number or cmd trigger or fuzzy command match",
+		"On user input: Number → execute menu item[n] | Text → case-insensitive substring match | Multiple matches → ask user to clarify | No match → show \"Not recognized\"",
+		"When executing a menu item: Check menu-handlers section below - extract any attributes from the selected menu item and follow the corresponding handler instructions",
+	)
+	w("<activation critical=\"MANDATORY\">\n")
+	for i, s := range steps {
+		w("  <step n=\"%d\">%s</step>\n", i+1, esc(s))
+	}
+	if len(handlers) > 0 {
+		w("  <menu-handlers>\n    <handlers>\n")
+		for _, a := range menuItemAttrs {
+			if handlers[a.attr] {
+				w("      <handler type=\"%s\">%s</handler>\n", a.attr, esc(menuHandlerText[a.attr]))
+			}
+		}
+		w("    </handlers>\n  </menu-handlers>\n")
+	}
+	w("  <rules>\n")
+	for _, r := range []string{
+		"ALWAYS communicate in {communication_language} UNLESS contradicted by communication_style.",
+		"Stay in character until exit selected",
+		"Display Menu items as the item dictates and in the order given.",
+		"Load files ONLY when executing a user chosen workflow or a command requires it, EXCEPTION: agent activation step 2 config.yaml",
+	} {
+		w("    <r>%s</r>\n", esc(r))
+	}
+	w("  </rules>\n</activation>\n")
+
+	w("<persona>\n")
+	for _, k := range []string{"role", "identity", "communication_style", "principles"} {
+		v := persona[k]
+		text := yamlString(v)
+		if list := yamlList(v); list != nil {
+			text = "- " + strings.Join(yamlStrings(list), "\n- ")
+		}
+		w("  <%s>%s</%s>\n", k, esc(text), k)
+	}
+	w("</persona>\n")
+
+	if prompts := yamlList(agent["prompts"]); len(prompts) > 0 {
+		w("<prompts>\n")
+		for _, p := range prompts {
+			pm := yamlMap(p)
+			w("  <prompt id=\"%s\">\n%s\n  </prompt>\n", attr(yamlString(pm["id"])), esc(yamlString(pm["content"])))
+		}
+		w("</prompts>\n")
+	}
+
+	w("<menu>\n")
+	w("  <item cmd=\"MH or fuzzy match on menu or help\">[MH] Redisplay Menu Help</item>\n")
+	w("  <item cmd=\"CH or fuzzy match on chat\">[CH] Chat with the Agent about anything</item>\n")
+	for i, it := range menu {
+		item := yamlMap(it)
+		trigger := yamlString(item["trigger"])
+		if trigger == "" {
+			return "", fmt.Errorf("menu[%d]: missing trigger", i)
+		}
+		w("  <item cmd=\"%s\"", attr(trigger))
+		for _, a := range menuItemAttrs {
+			if v := yamlString(item[a.yaml]); v != "" {
+				w(" %s=\"%s\"", a.attr, attr(v))
+			}
+		}
+		w(">%s</item>\n", esc(yamlString(item["description"])))
+	}
+	w("  <item cmd=\"DA or fuzzy match on exit, leave, goodbye or dismiss agent\">[DA] Dismiss Agent</item>\n")
+	w("</menu>\n")
+	w("</agent>\n")
+	return b.String(), nil
+}
+
+// menuHandlerText explains each menu item attribute to the model, following
+// the handlers the BMAD installer emits. Workflow items point to converted
+// skills, so the handlers do not load the workflow.xml engine.
+var menuHandlerText = map[string]string{
+	"workflow": `When menu item has: workflow="path/to/SKILL.md":
+1. Read the complete skill file at that path: it holds the whole workflow, its instructions, steps and templates
+2. There is no workflow.xml engine to load: follow the workflow steps sequentially yourself
+3. Save outputs after completing EACH workflow step (never batch multiple steps together)
+4. If the workflow path is "todo", inform user the workflow hasn't been implemented yet`,
+	"exec": `When menu item or handler has: exec="path/to/file.md":
+1. Read fully and follow the file at that path
+2. Process the complete file and follow all instructions within it
+3. If there is data="some/path/data-foo.md" with the same item, pass that data path to the executed file as context.`,
+	"tmpl": `When menu item has: tmpl="path/to/template.md":
+1. Load the template file
+2. Use it as the structure for the document being produced`,
+	"data": `When menu item has: data="path/to/file.json|yaml|yml|csv|xml":
+Load the file first, parse according to extension, and make it available as {data} to subsequent handler operations`,
+	"action": `When menu item has: action="#id" → Find prompt with id="id" in current agent XML, follow its content
+When menu item has: action="text" → Follow the text directly as an inline instruction`,
+	"validate-workflow": `When menu item has: validate-workflow="path/to/SKILL.md":
+1. Read the skill file at that path and find its Validation Checklist section
+2. Check the document the workflow produced against every item of that checklist
+3. Identify the file to validate from the checklist context, or else ask the user to specify`,
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompileAgentYAMLHandlers(t *testing.T) {
+	src := `agent:
+  metadata:
+    name: Mary
+    title: Business Analyst
+  persona:
+    role: Analyst
+  menu:
+    - trigger: brainstorm
+      workflow: "{project-root}/_bmad/core/workflows/brainstorming/workflow.yaml"
+      description: Brainstorm
+    - trigger: validate
+      validate-workflow: "{project-root}/_bmad/bmm/workflows/prd/workflow.yaml"
+      description: Validate the PRD
+`
+	xml, err := compileAgentYAML([]byte(src), "bmm", "analyst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range []string{`<handler type="workflow">`, `<handler type="validate-workflow">`} {
+		if !strings.Contains(xml, h) {
+			t.Errorf("missing %s", h)
+		}
+	}
+	// The handlers must not send the model to BMAD files that are not installed.
+	p := vibePaths{home: "~/.vibe", skills: skillIndex{
+		"core/workflows/brainstorming/workflow.yaml": "bmad-core-brainstorming",
+		"bmm/workflows/prd/workflow.yaml":            "bmad-bmm-prd",
+	}}
+	if _, unresolved := p.render(xml); len(unresolved) > 0 {
+		t.Errorf("unresolved references: %v", unresolved)
+	}
+}
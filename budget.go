@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// --- Token budgets ---
+
+// tokenizer estimates the number of tokens a model would see for a text.
+// None of these is exact; they are cheap approximations good enough to spot
+// artifacts that will not fit a context window.
+type tokenizer func(text string) int
+
+// tokenizers are selected with -tokenizer or [budgets] tokenizer.
+var tokenizers = map[string]tokenizer{
+	// ~4 characters per token, the usual rule of thumb for English prose.
+	"chars": func(text string) int {
+		return int(math.Ceil(float64(len([]rune(text))) / 4))
+	},
+	// ~0.75 words per token.
+	"words": func(text string) int {
+		return int(math.Ceil(float64(len(strings.Fields(text))) * 4 / 3))
+	},
+	// Closer to BPE on markdown, XML and code: every punctuation mark and
+	// non-ASCII character is a token, words are split every 6 characters.
+	"mixed": func(text string) int {
+		n, run := 0, 0
+		flush := func() {
+			n += (run + 5) / 6
+			run = 0
+		}
+		for _, r := range text {
+			switch {
+			case r < 128 && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+				run++
+			case unicode.IsSpace(r):
+				flush()
+			default:
+				flush()
+				n++
+			}
+		}
+		flush()
+		return n
+	},
+}
+
+const defaultTokenizer = "chars"
+
+func tokenizerNames() string {
+	var names []string
+	for n := range tokenizers {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// budget is the token limit of one artifact kind; 0 disables a threshold.
+type budget struct {
+	Warn  int
+	Error int
+}
+
+// defaultBudgets keep agents well inside a 128k-token Mistral context, with
+// room left for the conversation.
+var defaultBudgets = map[string]budget{
+	"prompt": {Warn: 24000, Error: 96000},
+	"skill":  {Warn: 16000, Error: 64000},
+}
+
+// budgetFor returns the budget of kind: [budgets.<kind>] over the defaults.
+func (cfg *config) budgetFor(kind string) budget {
+	b := defaultBudgets[kind]
+	if o, ok := cfg.file.Budgets.Limits[kind]; ok {
+		if o.Warn != nil {
+			b.Warn = *o.Warn
+		}
+		if o.Error != nil {
+			b.Error = *o.Error
+		}
+	}
+	return b
+}
+
+// artifactSize is the estimated size of one generated prompt or skill.
+type artifactSize struct {
+	Kind   string // "prompt" or "skill"
+	Name   string
+	Path   string
+	Bytes  int
+	Tokens int
+}
+
+// measureArtifacts estimates every prompt and SKILL.md under vibe-home,
+// largest first.
+func measureArtifacts(cfg *config, count tokenizer) []artifactSize {
+	var out []artifactSize
+	add := func(kind, name, path string) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return
+		}
+		out = append(out, artifactSize{Kind: kind, Name: name, Path: path, Bytes: len(data), Tokens: count(string(data))})
+	}
+	for _, p := range cfg.target.prompts(cfg.vibeHome) {
+		add("prompt", strings.TrimSuffix(filepath.Base(p), ".md"), p)
+	}
+	skills, _ := filepath.Glob(filepath.Join(cfg.vibeHome, "skills", "bmad-*", "SKILL.md"))
+	for _, p := range skills {
+		add("skill", filepath.Base(filepath.Dir(p)), p)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Tokens != out[j].Tokens {
+			return out[i].Tokens > out[j].Tokens
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// checkBudgets reports artifacts over their budget and prints the largest.
+func checkBudgets(cfg *config, report *conversionReport) {
+	name := firstNonEmpty(cfg.tokenizer, defaultTokenizer)
+	sizes := measureArtifacts(cfg, tokenizers[name])
+
+	for _, a := range sizes {
+		b := cfg.budgetFor(a.Kind)
+		rel := cfg.manifestPath(a.Path)
+		switch {
+		case b.Error > 0 && a.Tokens > b.Error:
+			report.err(diagTokenBudget, rel, "~%d tokens exceeds the %s budget of %d", a.Tokens, a.Kind, b.Error)
+		case b.Warn > 0 && a.Tokens > b.Warn:
+			report.warn(diagTokenBudget, rel, "~%d tokens exceeds the %s warning budget of %d", a.Tokens, a.Kind, b.Warn)
+		}
+	}
+
+	if len(sizes) == 0 {
+		return
+	}
+	top := sizes
+	if len(top) > 10 {
+		top = top[:10]
+	}
+	cfg.printf("   📏 Largest artifacts (~tokens, %s tokenizer):\n", name)
+	cfg.printf("   %3s %7s  %9s  %9s  %-6s %s\n", "#", "tokens", "size", "of budget", "kind", "name")
+	for i, a := range top {
+		pct := ""
+		if b := cfg.budgetFor(a.Kind); b.Error > 0 {
+			pct = fmt.Sprintf("%d%%", a.Tokens*100/b.Error)
+		}
+		cfg.printf("   %2d. %7d  %9s  %9s  %-6s %s\n", i+1, a.Tokens, humanSize(int64(a.Bytes)), pct, a.Kind, a.Name)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// --- Agent bundle model ---
+
+// agentBundle is the parsed form of a bmad-bundles agent file. A bundle is
+// either a bare <agent> element or an <agent-bundle> wrapping the agent and
+// the files it depends on.
+type agentBundle struct {
+	Agent        bundleAgent
+	Dependencies []bundleFile
+}
+
+type bundleAgent struct {
+	XMLName         xml.Name        `xml:"agent"`
+	ID              string          `xml:"id,attr"`
+	Name            string          `xml:"name,attr"`
+	Title           string          `xml:"title,attr"`
+	Icon            string          `xml:"icon,attr"`
+	Description     string          `xml:"description,attr"`
+	Activation      agentActivation `xml:"activation"`
+	Persona         agentPersona    `xml:"persona"`
+	CriticalActions []string        `xml:"critical_actions>i"`
+	Menu            []menuItem      `xml:"menu>item"`
+}
+
+type agentActivation struct {
+	Critical string           `xml:"critical,attr"`
+	Steps    []activationStep `xml:"step"`
+	Rules    []string         `xml:"rules>r"`
+	Handlers []menuHandler    `xml:"menu-handlers>handlers>handler"`
+}
+
+type activationStep struct {
+	N    string `xml:"n,attr"`
+	Text string `xml:",innerxml"`
+}
+
+type menuHandler struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",innerxml"`
+}
+
+type agentPersona struct {
+	Role               string `xml:"role"`
+	Identity           string `xml:"identity"`
+	CommunicationStyle string `xml:"communication_style"`
+	Principles         string `xml:"principles"`
+}
+
+type menuItem struct {
+	Cmd      string `xml:"cmd,attr"`
+	Workflow string `xml:"workflow,attr"`
+	Exec     string `xml:"exec,attr"`
+	Tmpl     string `xml:"tmpl,attr"`
+	Data     string `xml:"data,attr"`
+	Action   string `xml:"action,attr"`
+	Label    string `xml:",chardata"`
+}
+
+type bundleFile struct {
+	ID      string `xml:"id,attr"`
+	Type    string `xml:"type,attr"`
+	Content string `xml:",chardata"`
+}
+
+// parseAgentBundle decodes a bundle XML document. Unlike a regex scan it sees
+// attributes on a nested <agent>, single-quoted values and escaped entities,
+// and it fails on malformed documents instead of returning empty metadata.
+func parseAgentBundle(raw []byte) (*agentBundle, error) {
+	d := xml.NewDecoder(bytes.NewReader(raw))
+	d.Entity = xml.HTMLEntity
+
+	var b agentBundle
+	found := false
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if found {
+			return nil, fmt.Errorf("unexpected element <%s> after root", se.Name.Local)
+		}
+		switch se.Name.Local {
+		case "agent":
+			if err := d.DecodeElement(&b.Agent, &se); err != nil {
+				return nil, err
+			}
+		case "agent-bundle":
+			var wrapper struct {
+				Agent        *bundleAgent `xml:"agent"`
+				Dependencies []bundleFile `xml:"dependencies>file"`
+			}
+			if err := d.DecodeElement(&wrapper, &se); err != nil {
+				return nil, err
+			}
+			if wrapper.Agent == nil {
+				return nil, errors.New("<agent-bundle> has no <agent> element")
+			}
+			b.Agent = *wrapper.Agent
+			b.Dependencies = wrapper.Dependencies
+		default:
+			return nil, fmt.Errorf("unexpected root element <%s>", se.Name.Local)
+		}
+		found = true
+	}
+	if !found {
+		return nil, errors.New("no <agent> element found")
+	}
+	return &b, nil
+}
+
+// meta flattens the bundle into the metadata used by the TOML and prompt
+// generators. The slug stands in for a missing title and the persona role
+// for a missing description.
+func (b *agentBundle) meta(slug string) agentMeta {
+	a := b.Agent
+	title := strings.TrimSpace(a.Title)
+	if title == "" {
+		title = toTitle(slug)
+	}
+	desc := strings.TrimSpace(a.Description)
+	if desc == "" {
+		desc = firstLine(a.Persona.Role)
+	}
+	return agentMeta{
+		Slug:        slug,
+		Name:        strings.TrimSpace(a.Name),
+		Title:       title,
+		Icon:        strings.TrimSpace(a.Icon),
+		Description: desc,
+	}
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	return s
+}
+
+// --- Menu resolution ---
+
+// skillIndex maps a BMAD source reference, relative to src/ (e.g.
+// "bmm/workflows/4-impl/dev-story/workflow.yaml"), to the slug of the skill
+// generated from it.
+type skillIndex map[string]string
+
+// buildSkillIndex lists the workflows and tasks of the converted modules so
+// menu items can be resolved before Phase 2 writes the skills.
+func buildSkillIndex(methodDir string, modules []string) skillIndex {
+	idx := make(skillIndex)
+	for _, mod := range modules {
+		workflowsDir := filepath.Join(methodDir, "src", mod, "workflows")
+		filepath.Walk(workflowsDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || !isWorkflowFile(info.Name()) {
+				return nil
+			}
+			rel, _ := filepath.Rel(workflowsDir, path)
+			idx[filepath.ToSlash(filepath.Join(mod, "workflows", rel))] = buildSkillSlug(mod, rel, info.Name())
+			return nil
+		})
+
+		entries, _ := os.ReadDir(filepath.Join(methodDir, "src", mod, "tasks"))
+		for _, e := range entries {
+			if !e.IsDir() && isTaskFile(e.Name()) {
+				idx[mod+"/tasks/"+e.Name()] = taskSkillSlug(mod, e.Name())
+			}
+		}
+	}
+	return idx
+}
+
+// resolve maps an installed BMAD path such as
+// "{project-root}/_bmad/bmm/workflows/x/workflow.yaml" to a skill slug. Each
+// "/workflows/" or "/tasks/" segment of the path is tried in turn, since a
+// directory may carry the other kind's name.
+func (idx skillIndex) resolve(ref string) (string, bool) {
+	ref = filepath.ToSlash(strings.TrimSpace(ref))
+	for _, kind := range []string{"/workflows/", "/tasks/"} {
+		for from := 0; ; {
+			i := strings.Index(ref[from:], kind)
+			if i < 0 {
+				break
+			}
+			i += from
+			mod := ref[strings.LastIndex(ref[:i], "/")+1 : i]
+			if slug, ok := idx[mod+ref[i:]]; ok {
+				return slug, true
+			}
+			from = i + 1
+		}
+	}
+	return "", false
+}
+
+// menuEntry is a menu item with its workflow reference resolved.
+type menuEntry struct {
+	Trigger     string
+	Description string
+	Ref         string // BMAD path the item points to, empty for inline actions
+	Skill       string // resolved skill slug, empty if unresolved or inline
+}
+
+// resolveMenu resolves the workflow/exec targets of every menu item. Items
+// pointing at something that is not converted are returned as unresolved.
+func resolveMenu(items []menuItem, idx skillIndex) (entries []menuEntry, unresolved []menuEntry) {
+	for _, it := range items {
+		e := menuEntry{
+			Trigger:     strings.TrimSpace(it.Cmd),
+			Description: strings.Join(strings.Fields(it.Label), " "),
+			Ref:         strings.TrimSpace(it.Workflow),
+		}
+		if e.Ref == "" {
+			e.Ref = strings.TrimSpace(it.Exec)
+		}
+		if strings.EqualFold(e.Ref, "todo") {
+			e.Ref = ""
+		}
+		if e.Ref != "" {
+			slug, ok := idx.resolve(e.Ref)
+			if ok {
+				e.Skill = slug
+			} else {
+				unresolved = append(unresolved, e)
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, unresolved
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseAgentBundle(t *testing.T) {
+	tests := []struct {
+		name, xml string
+		want      agentMeta
+		menu      int
+		err       string // substring of the expected error
+	}{
+		{
+			name: "bare agent",
+			xml: `<agent id="pm.agent.yaml" name="John" title="Product Manager" icon="📋">
+  <persona><role>Investigative product strategist</role></persona>
+  <menu><item cmd="*prd" exec="{project-root}/_bmad/bmm/workflows/prd/workflow.md">Create PRD</item></menu>
+</agent>`,
+			want: agentMeta{Slug: "pm", Name: "John", Title: "Product Manager", Icon: "📋", Description: "Investigative product strategist"},
+			menu: 1,
+		},
+		{
+			name: "nested agent attributes",
+			xml: `<?xml version="1.0"?>
+<agent-bundle>
+  <agent id="dev" name="Amelia" title="Developer Agent" icon="💻" description="Implements stories">
+    <menu><item cmd="*dev-story" workflow="x/workflow.yaml">Develop</item><item cmd="*exit">Exit</item></menu>
+  </agent>
+  <dependencies><file id="a.md" type="md">content</file></dependencies>
+</agent-bundle>`,
+			want: agentMeta{Slug: "dev", Name: "Amelia", Title: "Developer Agent", Icon: "💻", Description: "Implements stories"},
+			menu: 2,
+		},
+		{
+			name: "single quotes and entities",
+			xml:  `<agent name='John &amp; Co' title='R&amp;D &quot;Lead&quot; &mdash; &lt;core&gt;'/>`,
+			want: agentMeta{Slug: "lead", Name: "John & Co", Title: `R&D "Lead" — <core>`},
+		},
+		{
+			name: "missing title falls back to the slug",
+			xml:  `<agent name="Sam"/>`,
+			want: agentMeta{Slug: "tech-writer", Name: "Sam", Title: "Tech Writer"},
+		},
+		{name: "no agent element", xml: `<?xml version="1.0"?><!-- empty -->`, err: "no <agent> element"},
+		{name: "bundle without agent", xml: `<agent-bundle><dependencies/></agent-bundle>`, err: "has no <agent> element"},
+		{name: "other root", xml: `<team name="x"/>`, err: "unexpected root element <team>"},
+		{name: "truncated", xml: `<agent name="John" title="PM"><persona><role>Strat`, err: "unexpected EOF"},
+		{name: "unterminated attribute", xml: `<agent name="John`, err: "unexpected EOF"},
+		{name: "mismatched tags", xml: `<agent><persona></agent>`, err: "element <persona> closed by </agent>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := parseAgentBundle([]byte(tt.xml))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := b.meta(tt.want.Slug); got != tt.want {
+				t.Errorf("meta = %+v\nwant   %+v", got, tt.want)
+			}
+			if len(b.Agent.Menu) != tt.menu {
+				t.Errorf("menu has %d items, want %d", len(b.Agent.Menu), tt.menu)
+			}
+		})
+	}
+}
+
+func TestResolveMenu(t *testing.T) {
+	idx := skillIndex{
+		"bmm/workflows/4-impl/dev-story/workflow.yaml": "bmad-bmm-4-impl-dev-story",
+		"bmm/workflows/tasks/triage/workflow.md":       "bmad-bmm-tasks-triage",
+		"core/tasks/shard-doc.xml":                     "bmad-core-task-shard-doc",
+		"bmm/tasks/workflows/review.md":                "bmad-bmm-task-review",
+	}
+	items := []menuItem{
+		{Cmd: "*dev-story", Workflow: "{project-root}/_bmad/bmm/workflows/4-impl/dev-story/workflow.yaml", Label: "Implement\n  a story"},
+		{Cmd: "*shard", Exec: "{project-root}/_bmad/core/tasks/shard-doc.xml", Label: "Shard | split a document"},
+		{Cmd: "*triage", Exec: "{project-root}/_bmad/bmm/workflows/tasks/triage/workflow.md", Label: "Triage"},
+		{Cmd: "*review", Exec: "{project-root}/_bmad/bmm/tasks/workflows/review.md", Label: "Review"},
+		{Cmd: "*gone", Workflow: "{project-root}/_bmad/bmm/workflows/gone/workflow.yaml", Label: "Gone"},
+		{Cmd: "*later", Workflow: "todo", Label: "Not yet"},
+		{Cmd: "*chat", Action: "Talk it through", Label: "Chat"},
+	}
+	menu, unresolved := resolveMenu(items, idx)
+
+	want := []string{"bmad-bmm-4-impl-dev-story", "bmad-core-task-shard-doc", "bmad-bmm-tasks-triage", "bmad-bmm-task-review", "", "", ""}
+	for i, e := range menu {
+		if e.Skill != want[i] {
+			t.Errorf("%s: skill %q, want %q", e.Trigger, e.Skill, want[i])
+		}
+	}
+	if menu[0].Description != "Implement a story" {
+		t.Errorf("description = %q", menu[0].Description)
+	}
+	// Only the missing reference is reported (BV022); todo and inline actions are not.
+	if len(unresolved) != 1 || unresolved[0].Trigger != "*gone" {
+		t.Errorf("unresolved = %+v", unresolved)
+	}
+
+	prompt := buildAgentPrompt("bmm", "pm", agentMeta{Title: "PM"}, menu, "<agent/>", "bmad-bundles", vibePaths{home: "~/.vibe"}, outputFolders{}, vibeTarget{})
+	for _, row := range []string{
+		"| `*dev-story` | Implement a story | `~/.vibe/skills/bmad-bmm-4-impl-dev-story/SKILL.md` |",
+		"| `*shard` | Shard \\| split a document | `~/.vibe/skills/bmad-core-task-shard-doc/SKILL.md` |",
+		"| `*gone` | Gone | (unavailable) |",
+		"| `*chat` | Chat | (inline action) |",
+	} {
+		if !strings.Contains(prompt, row) {
+			t.Errorf("menu table lacks %q:\n%s", row, prompt)
+		}
+	}
+}
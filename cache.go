@@ -0,0 +1,254 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --- Source cache ---
+//
+// Layout under the cache dir:
+//
+//	<repo>-<urlhash>.git          bare mirror, updated with git fetch
+//	<repo>-<urlhash>@<sha>/       checkout of one commit, reused across runs
+//
+// The checkout's modification time records when it was last used.
+
+// originHead holds, in a mirror, the commit of the remote default branch as
+// of the last fetch.
+const originHead = "refs/remotes/origin/HEAD"
+
+// defaultCacheDir returns $XDG_CACHE_HOME/bmad2vibe (or the platform cache dir).
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "bmad2vibe")
+}
+
+// cacheKey names a repository in the cache. The URL hash keeps forks of the
+// same repository apart.
+func cacheKey(repo *sourceRepo) string {
+	sum := sha256.Sum256([]byte(repo.URL))
+	return repo.Name + "-" + hex.EncodeToString(sum[:4])
+}
+
+// resolveCached points repo.Dir at a cached checkout of repo.Ref, fetching
+// updates first. When the fetch fails (offline), the refs already in the
+// mirror are used.
+func resolveCached(cfg *config, repo *sourceRepo) error {
+	if err := os.MkdirAll(cfg.cacheDir, 0o755); err != nil {
+		return err
+	}
+	key := cacheKey(repo)
+	mirror := filepath.Join(cfg.cacheDir, key+".git")
+
+	online := true
+	if !dirExists(mirror) {
+		cfg.printf("   📥 Cloning %s...\n", repo.URL)
+		tmp := mirror + ".tmp"
+		os.RemoveAll(tmp)
+		if err := runGit(cfg.gitOutput(), "", "clone", "-q", "--bare", repo.URL, tmp); err != nil {
+			os.RemoveAll(tmp)
+			return fmt.Errorf("clone %s: %v (no cached copy available)", repo.URL, err)
+		}
+		if err := os.Rename(tmp, mirror); err != nil {
+			return err
+		}
+	} else if cfg.verbose {
+		cfg.printf("   🔄 Fetching %s...\n", repo.URL)
+	}
+	// The mirror's own HEAD is fixed at clone time, so the remote default
+	// branch is fetched into originHead on every run.
+	if err := runGit(cfg.gitOutput(), mirror, "fetch", "-q", "--prune", "--tags", "origin", "+refs/heads/*:refs/heads/*", "+HEAD:"+originHead); err != nil {
+		online = false
+		cfg.printf("   ⚠️  Cannot reach %s — using cached copy\n", repo.URL)
+	}
+
+	ref := repo.Ref
+	if ref == "" {
+		ref = originHead
+		if _, err := resolveRef(mirror, ref); err != nil {
+			ref = "HEAD" // offline, with a mirror predating originHead
+		}
+	}
+	sha, err := resolveRef(mirror, ref)
+	if err != nil && online && repo.Ref != "" {
+		// Commits not reachable from a branch or tag are fetched on demand.
+		if runGit(cfg.gitOutput(), mirror, "fetch", "-q", "origin", repo.Ref) == nil {
+			sha, err = resolveRef(mirror, repo.Ref)
+		}
+	}
+	if err != nil {
+		if !online {
+			return fmt.Errorf("%v in the cached copy of %s (offline)", err, repo.URL)
+		}
+		return err
+	}
+
+	dir := filepath.Join(cfg.cacheDir, key+"@"+sha)
+	if !dirExists(dir) {
+		tmp := dir + ".tmp"
+		os.RemoveAll(tmp)
+		if err := runGit(cfg.gitOutput(), "", "clone", "-q", "--shared", "--no-checkout", mirror, tmp); err != nil {
+			os.RemoveAll(tmp)
+			return fmt.Errorf("checkout %s: %v", shortSHA(sha), err)
+		}
+		if err := runGit(cfg.gitOutput(), tmp, "checkout", "-q", "--detach", sha); err != nil {
+			os.RemoveAll(tmp)
+			return fmt.Errorf("checkout %s: %v", shortSHA(sha), err)
+		}
+		if err := os.Rename(tmp, dir); err != nil {
+			return err
+		}
+	} else if cfg.verbose {
+		cfg.printf("   ♻️  Reusing cached %s\n", dir)
+	}
+	now := time.Now()
+	os.Chtimes(dir, now, now)
+
+	repo.Dir, repo.SHA = dir, sha
+	cfg.printf("   📌 %s @ %s\n", repo.Name, shortSHA(sha))
+	return nil
+}
+
+// --- cache subcommand ---
+
+type cacheEntry struct {
+	Name     string
+	Path     string
+	Size     int64
+	LastUsed time.Time
+	Mirror   bool
+	Repo     string // cache key of the repository
+}
+
+func listCache(dir string) ([]cacheEntry, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []cacheEntry
+	for _, e := range entries {
+		if !e.IsDir() || strings.HasSuffix(e.Name(), ".tmp") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		ce := cacheEntry{Name: e.Name(), Path: filepath.Join(dir, e.Name()), LastUsed: info.ModTime()}
+		if strings.HasSuffix(e.Name(), ".git") {
+			ce.Mirror = true
+			ce.Repo = strings.TrimSuffix(e.Name(), ".git")
+		} else if i := strings.LastIndex(e.Name(), "@"); i > 0 {
+			ce.Repo = e.Name()[:i]
+		} else {
+			continue
+		}
+		ce.Size = dirSize(ce.Path)
+		out = append(out, ce)
+	}
+	return out, nil
+}
+
+func dirSize(dir string) int64 {
+	var n int64
+	filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			n += info.Size()
+		}
+		return nil
+	})
+	return n
+}
+
+func humanSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// runCache implements `bmad2vibe cache list|prune`.
+func runCache(args []string) {
+	if len(args) == 0 || (args[0] != "list" && args[0] != "prune") {
+		fmt.Fprintln(os.Stderr, "Usage: bmad2vibe cache list|prune [flags]")
+		os.Exit(2)
+	}
+	cmd := args[0]
+	fs := flag.NewFlagSet("cache "+cmd, flag.ExitOnError)
+	cacheDir := fs.String("cache-dir", defaultCacheDir(), "Source cache directory")
+	keep := fs.Int("keep", 1, "prune: checkouts to keep per repository, most recently used first")
+	all := fs.Bool("all", false, "prune: remove the whole cache, mirrors included")
+	dryRun := fs.Bool("dry-run", false, "prune: show what would be removed")
+	fs.Parse(args[1:])
+
+	entries, err := listCache(*cacheDir)
+	if err != nil {
+		log.Fatalf("cannot read cache: %v", err)
+	}
+
+	if cmd == "list" {
+		fmt.Printf("📦 Source cache: %s\n", *cacheDir)
+		if len(entries) == 0 {
+			fmt.Println("   (empty)")
+			return
+		}
+		var total int64
+		for _, e := range entries {
+			kind := "checkout"
+			if e.Mirror {
+				kind = "mirror"
+			}
+			fmt.Printf("   %-8s %-60s %10s  %s\n", kind, e.Name, humanSize(e.Size), e.LastUsed.Format("2006-01-02 15:04"))
+			total += e.Size
+		}
+		fmt.Printf("   Total: %s\n", humanSize(total))
+		return
+	}
+
+	// prune: keep the most recently used checkouts of each repository.
+	sort.Slice(entries, func(i, j int) bool { return entries[i].LastUsed.After(entries[j].LastUsed) })
+	kept := make(map[string]int)
+	var freed int64
+	removed := 0
+	for _, e := range entries {
+		if !*all {
+			if e.Mirror {
+				continue
+			}
+			if kept[e.Repo] < *keep {
+				kept[e.Repo]++
+				continue
+			}
+		}
+		if *dryRun {
+			fmt.Printf("   [DRY] Would remove %s\n", e.Path)
+		} else if err := os.RemoveAll(e.Path); err != nil {
+			fmt.Printf("   ❌ %s: %v\n", e.Name, err)
+			continue
+		}
+		freed += e.Size
+		removed++
+	}
+	fmt.Printf("🧹 Pruned %d cache entries, %s freed\n", removed, humanSize(freed))
+}
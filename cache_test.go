@@ -0,0 +1,198 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testRemote is a bare repository standing in for GitHub, fed from a work
+// tree.
+type testRemote struct {
+	t    *testing.T
+	work string
+	bare string
+}
+
+func newTestRemote(t *testing.T) *testRemote {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	for k, v := range map[string]string{
+		"GIT_AUTHOR_NAME": "test", "GIT_AUTHOR_EMAIL": "test@example.com",
+		"GIT_COMMITTER_NAME": "test", "GIT_COMMITTER_EMAIL": "test@example.com",
+		"GIT_CONFIG_GLOBAL": os.DevNull, "GIT_CONFIG_NOSYSTEM": "1",
+	} {
+		t.Setenv(k, v)
+	}
+	dir := t.TempDir()
+	r := &testRemote{t: t, work: filepath.Join(dir, "work"), bare: filepath.Join(dir, "remote.git")}
+	r.git("", "init", "-q", "--bare", "-b", "main", r.bare)
+	r.git("", "init", "-q", "-b", "main", r.work)
+	r.git(r.work, "remote", "add", "origin", r.bare)
+	return r
+}
+
+func (r *testRemote) git(dir string, args ...string) string {
+	r.t.Helper()
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// commit writes content to VERSION, commits and pushes it, and returns the
+// commit SHA.
+func (r *testRemote) commit(content string) string {
+	r.t.Helper()
+	os.WriteFile(filepath.Join(r.work, "VERSION"), []byte(content), 0o644)
+	r.git(r.work, "add", "VERSION")
+	r.git(r.work, "commit", "-q", "-m", content)
+	r.git(r.work, "push", "-q", "origin", "HEAD")
+	return r.git(r.work, "rev-parse", "HEAD")
+}
+
+func resolveTestSource(t *testing.T, cacheDir, url, ref string) *sourceRepo {
+	t.Helper()
+	repo := &sourceRepo{Name: "bmad-bundles", Flag: "bundles", URL: url, Ref: ref}
+	if err := resolveCached(&config{cacheDir: cacheDir}, repo); err != nil {
+		t.Fatalf("resolveCached(%q): %v", ref, err)
+	}
+	return repo
+}
+
+func checkoutVersion(t *testing.T, repo *sourceRepo) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(repo.Dir, "VERSION"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestResolveCachedPins(t *testing.T) {
+	r := newTestRemote(t)
+	v1 := r.commit("v1")
+	r.git(r.work, "tag", "v1.0.0")
+	r.git(r.work, "push", "-q", "origin", "v1.0.0")
+	v2 := r.commit("v2")
+	cache := t.TempDir()
+
+	tests := []struct {
+		name, ref, sha, version string
+	}{
+		{"default branch", "", v2, "v2"},
+		{"tag", "v1.0.0", v1, "v1"},
+		{"branch", "main", v2, "v2"},
+		{"full SHA", v1, v1, "v1"},
+		{"short SHA", v1[:10], v1, "v1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := resolveTestSource(t, cache, r.bare, tt.ref)
+			if repo.SHA != tt.sha {
+				t.Errorf("SHA = %s, want %s", repo.SHA, tt.sha)
+			}
+			if got := checkoutVersion(t, repo); got != tt.version {
+				t.Errorf("checkout has %q, want %q", got, tt.version)
+			}
+			if filepath.Base(repo.Dir) != cacheKey(repo)+"@"+tt.sha {
+				t.Errorf("Dir = %s", repo.Dir)
+			}
+		})
+	}
+
+	repo := &sourceRepo{Name: "bmad-bundles", URL: r.bare, Ref: "v9.9.9"}
+	if err := resolveCached(&config{cacheDir: cache}, repo); err == nil {
+		t.Error("missing ref: want an error")
+	}
+}
+
+func TestResolveCachedReuseAndUpdates(t *testing.T) {
+	r := newTestRemote(t)
+	v1 := r.commit("v1")
+	cache := t.TempDir()
+
+	first := resolveTestSource(t, cache, r.bare, "")
+	marker := filepath.Join(first.Dir, "marker")
+	os.WriteFile(marker, nil, 0o644)
+	second := resolveTestSource(t, cache, r.bare, "")
+	if second.Dir != first.Dir || second.SHA != v1 {
+		t.Fatalf("second run: %s @ %s, want %s @ %s", second.Dir, second.SHA, first.Dir, v1)
+	}
+	if !fileExists(marker) {
+		t.Error("checkout was recreated instead of reused")
+	}
+
+	// A new commit on the default branch is picked up by the next run.
+	v2 := r.commit("v2")
+	if repo := resolveTestSource(t, cache, r.bare, ""); repo.SHA != v2 {
+		t.Errorf("after push: SHA = %s, want %s", repo.SHA, v2)
+	}
+
+	// So is a change of default branch, which the mirror's HEAD does not follow.
+	r.git(r.work, "checkout", "-q", "-b", "develop")
+	v3 := r.commit("v3")
+	r.git(r.bare, "symbolic-ref", "HEAD", "refs/heads/develop")
+	if repo := resolveTestSource(t, cache, r.bare, ""); repo.SHA != v3 {
+		t.Errorf("after default branch change: SHA = %s, want %s", repo.SHA, v3)
+	}
+
+	// Offline, the last fetched state is used.
+	os.RemoveAll(r.bare)
+	repo := resolveTestSource(t, cache, r.bare, "")
+	if repo.SHA != v3 || checkoutVersion(t, repo) != "v3" {
+		t.Errorf("offline: SHA = %s, want %s", repo.SHA, v3)
+	}
+	if repo := resolveTestSource(t, cache, r.bare, v1); repo.SHA != v1 {
+		t.Errorf("offline pin: SHA = %s, want %s", repo.SHA, v1)
+	}
+}
+
+func TestCachePrune(t *testing.T) {
+	r := newTestRemote(t)
+	v1 := r.commit("v1")
+	v2 := r.commit("v2")
+	v3 := r.commit("v3")
+	cache := t.TempDir()
+
+	var dirs []string
+	for i, sha := range []string{v1, v2, v3} {
+		repo := resolveTestSource(t, cache, r.bare, sha)
+		used := time.Now().Add(time.Duration(i-3) * time.Hour) // v3 most recent
+		os.Chtimes(repo.Dir, used, used)
+		dirs = append(dirs, repo.Dir)
+	}
+	mirror := filepath.Join(cache, cacheKey(&sourceRepo{Name: "bmad-bundles", URL: r.bare})+".git")
+
+	runCache([]string{"prune", "-cache-dir", cache, "-keep", "2", "-dry-run"})
+	for _, d := range dirs {
+		if !dirExists(d) {
+			t.Fatalf("dry run removed %s", d)
+		}
+	}
+
+	runCache([]string{"prune", "-cache-dir", cache, "-keep", "2"})
+	if dirExists(dirs[0]) {
+		t.Error("least recently used checkout kept")
+	}
+	if !dirExists(dirs[1]) || !dirExists(dirs[2]) {
+		t.Error("recent checkouts removed")
+	}
+	if !dirExists(mirror) {
+		t.Error("mirror removed without -all")
+	}
+
+	runCache([]string{"prune", "-cache-dir", cache, "-all"})
+	if entries, _ := listCache(cache); len(entries) != 0 {
+		t.Errorf("after -all: %d entries left", len(entries))
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// --- Claude Code target ---
+
+// claudeTarget installs into a Claude Code directory: agents/<slug>.md
+// subagents (frontmatter + system prompt) and skills/. The index is a
+// section of CLAUDE.md, which Claude Code loads at startup.
+type claudeTarget struct{}
+
+// claudeToolNames maps Vibe tools to Claude Code tools.
+var claudeToolNames = map[string]string{
+	"read_file":         "Read",
+	"write_file":        "Write",
+	"search_replace":    "Edit",
+	"grep":              "Grep",
+	"list_dir":          "Glob",
+	"bash":              "Bash",
+	"ask_user_question": "AskUserQuestion",
+	"task":              "Task",
+	"todo":              "TodoWrite",
+}
+
+// claudeTools are the Claude Code tools an agent or skill can list.
+var claudeTools = map[string]bool{
+	"Read": true, "Write": true, "Edit": true, "Grep": true, "Glob": true, "Bash": true,
+	"AskUserQuestion": true, "Task": true, "TodoWrite": true,
+	"WebFetch": true, "WebSearch": true, "NotebookEdit": true, "Skill": true,
+}
+
+// claudeAgentKeys are the frontmatter keys of a Claude Code subagent.
+var claudeAgentKeys = map[string]bool{
+	"name": true, "description": true, "tools": true, "disallowedTools": true,
+	"model": true, "permissionMode": true, "skills": true, "color": true,
+}
+
+var claudePermissionModes = map[string]bool{
+	"default": true, "acceptEdits": true, "bypassPermissions": true, "plan": true, "dontAsk": true,
+}
+
+func (claudeTarget) name() string    { return "claude" }
+func (claudeTarget) product() string { return "Claude Code" }
+func (claudeTarget) short() string   { return "Claude Code" }
+func (claudeTarget) dir() string     { return ".claude" }
+
+func (claudeTarget) tool(name string) string {
+	if n, ok := claudeToolNames[name]; ok {
+		return n
+	}
+	return name
+}
+
+func (claudeTarget) tools() map[string]bool { return claudeTools }
+
+// agentFiles renders a subagent. Provenance goes in an HTML comment at the
+// top of the prompt; auto_approve becomes permissionMode acceptEdits.
+func (t claudeTarget) agentFiles(a agentSpec) []namedContent {
+	var b strings.Builder
+	w := func(f string, a ...any) { fmt.Fprintf(&b, f, a...) }
+
+	w("---\n")
+	w("name: %s\n", a.Slug)
+	w("description: %s\n", yamlQuote(oneLine(a.Description)))
+	w("tools: %s\n", strings.Join(mapTools(t, a.Policy.Tools), ", "))
+	if a.Policy.AutoApprove {
+		w("permissionMode: acceptEdits\n")
+	}
+	w("---\n\n")
+	if len(a.Comments) > 0 {
+		w("<!--\n")
+		for _, c := range a.Comments {
+			w("%s\n", strings.ReplaceAll(commentLine(c), "-->", "-- >"))
+		}
+		w("-->\n\n")
+	}
+	w("%s", a.Prompt)
+	return []namedContent{{name: t.agentPath(a.Slug), content: b.String()}}
+}
+
+func (claudeTarget) agentPath(slug string) string { return "agents/" + slug + ".md" }
+
+func (t claudeTarget) promptPath(slug string) string { return t.agentPath(slug) }
+
+func (claudeTarget) prompts(home string) []string {
+	files, _ := filepath.Glob(filepath.Join(home, "agents", "bmad-*.md"))
+	return files
+}
+
+func (t claudeTarget) listAgents(home string) []agentEntry {
+	var out []agentEntry
+	for _, f := range t.prompts(home) {
+		data, _ := os.ReadFile(f)
+		front, body, ok := splitFrontmatter(string(data))
+		if !ok || !claudeGenerated(body) {
+			continue
+		}
+		doc, _ := parseYAML(front)
+		fm := yamlMap(doc)
+		e := agentEntry{
+			Slug:        strings.TrimSuffix(filepath.Base(f), ".md"),
+			Description: yamlString(fm["description"]),
+			Shortcut:    strings.Contains(body, shortcutMarker),
+		}
+		for _, line := range strings.Split(body, "\n") {
+			if h, ok := strings.CutPrefix(line, "# "); ok {
+				e.Name = strings.TrimSpace(h)
+				break
+			}
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// claudeGenerated reports whether a subagent body starts with the
+// provenance comment agentFiles writes.
+func claudeGenerated(body string) bool {
+	lines := strings.SplitN(strings.TrimLeft(body, "\n"), "\n", 3)
+	return len(lines) >= 2 && lines[0] == "<!--" && generatedAgent(lines[1])
+}
+
+func (t claudeTarget) validateAgents(cfg *config, report *conversionReport) (int, int) {
+	files := t.prompts(cfg.vibeHome)
+	for _, f := range files {
+		data, _ := os.ReadFile(f)
+		slug := strings.TrimSuffix(filepath.Base(f), ".md")
+		checkClaudeAgent(cfg, "agents/"+slug+".md", slug, string(data), report)
+	}
+	return len(files), len(files)
+}
+
+// checkClaudeAgent validates the subagent rel (relative to the install dir).
+func checkClaudeAgent(cfg *config, rel, slug, content string, report *conversionReport) {
+	front, body, ok := splitFrontmatter(content)
+	if !ok {
+		report.err(diagInvalidFrontmatter, rel, "no YAML frontmatter (--- block at the top of the file)")
+		return
+	}
+	// The frontmatter starts on the second line of the file.
+	line := func(key string) int {
+		if n := yamlKeyLine(front, key); n > 0 {
+			return n + 1
+		}
+		return 0
+	}
+
+	parsed, err := parseYAML(front)
+	if err != nil {
+		reportYAMLError(rel, err, report)
+		return
+	}
+	fm := yamlMap(parsed)
+	if fm == nil {
+		report.err(diagInvalidFrontmatter, rel, "frontmatter is not a YAML mapping")
+		return
+	}
+	for _, k := range sortedKeys(fm) {
+		if !claudeAgentKeys[k] {
+			report.warnAt(diagUnknownKey, rel, line(k), "unknown frontmatter key %q", k)
+		}
+	}
+
+	for _, k := range []string{"name", "description"} {
+		if strings.TrimSpace(yamlString(fm[k])) == "" {
+			report.err(diagMissingField, rel, "missing field %q", k)
+		}
+	}
+	if name := yamlString(fm["name"]); name != "" && name != slug {
+		report.errAt(diagInvalidField, rel, line("name"), "name %q does not match the file name %q", name, slug)
+	}
+	if mode := yamlString(fm["permissionMode"]); fm["permissionMode"] != nil && !claudePermissionModes[mode] {
+		report.errAt(diagInvalidField, rel, line("permissionMode"), "invalid permissionMode %q", mode)
+	}
+	if tools, ok := fm["tools"]; ok {
+		s, isStr := tools.(string)
+		if !isStr {
+			report.errAt(diagInvalidField, rel, line("tools"), "tools: want a comma-separated string")
+		}
+		for _, n := range splitTrim(s, ",") {
+			if !claudeTools[n] {
+				report.warnAt(diagUnknownTool, rel, line("tools"), "tools: unknown tool %q", n)
+			}
+		}
+	}
+
+	if len(strings.TrimSpace(body)) < 50 {
+		report.warn(diagSmallPrompt, rel, "suspiciously small prompt (%d bytes)", len(strings.TrimSpace(body)))
+	}
+	if strings.Contains(body, shortcutMarker) {
+		if m := shortcutSkillRef.FindStringSubmatch(body); m != nil && !dirExists(filepath.Join(cfg.vibeHome, "skills", m[1])) {
+			report.err(diagMissingSkill, rel, "skill %s not found", m[1])
+		}
+	}
+}
+
+func (claudeTarget) runtime() (string, []string) {
+	return "You are running inside **Claude Code**, NOT Mistral Vibe/Cursor/Windsurf.", []string{
+		"| `ask_user_question` | `AskUserQuestion` tool |",
+		"| `workflow.xml` engine | Follow workflow steps sequentially |",
+		"| `task` tool (subagent) | `Task` tool for delegation |",
+	}
+}
+
+func (claudeTarget) index() string { return "CLAUDE.md" }
+
+// CLAUDE.md is always the user's, so bmad2vibe only maintains a section of
+// it. ~/.claude/CLAUDE.md is loaded in every project: a global install only
+// touches it with -global-index.
+func (claudeTarget) sharedIndex(string) bool { return true }
+func (claudeTarget) globalIndexOptIn() bool  { return true }
+
+func (claudeTarget) launch(slug string) string { return "@agent-" + slug }
+func (claudeTarget) launchHelp() string {
+	return "Launch: mention `@agent-<name>` in a prompt, or ask Claude Code to use the subagent."
+}
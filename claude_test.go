@@ -0,0 +1,149 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTree creates files, given relative to dir.
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// testSources is a small BMAD install: one bundled agent whose menu points
+// at a workflow, and one task.
+func testSources(t *testing.T) (bundles, method string) {
+	t.Helper()
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"bundles/bmm/agents/pm.xml": `<agent-bundle><agent id="pm" name="John" title="Product Manager" icon="📋">
+  <persona><role>Product strategist</role><identity>Veteran PM who ships.</identity></persona>
+  <menu><item cmd="*prd" exec="{project-root}/_bmad/bmm/workflows/prd/workflow.md">Create a PRD</item></menu>
+</agent></agent-bundle>`,
+		"method/src/bmm/workflows/prd/workflow.md": "---\nname: prd\ndescription: Write a product requirements document\n---\n\n# PRD\n\nAsk the user about the product.\n",
+		"method/src/bmm/tasks/review.md":           "# Review a document\n\nRead it and comment.\n",
+	})
+	return filepath.Join(dir, "bundles"), filepath.Join(dir, "method")
+}
+
+// runTestConversion converts the sources for t into home, as main does.
+func runTestConversion(t *testing.T, tgt target, scope, home, root, bundles, method string, globalIndex bool) *conversionReport {
+	t.Helper()
+	prev, err := loadManifest(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config{
+		vibeHome: home, modules: []string{"bmm"}, target: tgt, scope: scope, projectRoot: root, globalIndex: globalIndex,
+		out: io.Discard, file: &fileConfig{}, tokenizer: defaultTokenizer,
+		bundles: sourceRepo{Name: "bmad-bundles", Dir: bundles}, method: sourceRepo{Name: "BMAD-METHOD", Dir: method},
+		prevManifest: prev, manifest: newManifest(),
+	}
+	cfg.paths = newVibePaths(home, scope, root)
+	cfg.paths.skills = buildSkillIndex(method, cfg.modules)
+	report := &conversionReport{}
+	convert(cfg, bundles, method, cfg.paths.skills, report)
+	if len(report.errors) > 0 {
+		t.Fatalf("conversion errors: %v", report.errors)
+	}
+	return report
+}
+
+func TestClaudeTargetLayout(t *testing.T) {
+	bundles, method := testSources(t)
+	home := filepath.Join(t.TempDir(), ".claude")
+	runTestConversion(t, claudeTarget{}, scopeGlobal, home, "", bundles, method, false)
+
+	read := func(rel string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(home, filepath.FromSlash(rel)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	agent := read("agents/bmad-bmm-pm.md")
+	front, body, ok := splitFrontmatter(agent)
+	if !ok {
+		t.Fatalf("subagent has no frontmatter:\n%s", agent)
+	}
+	for _, line := range []string{"name: bmad-bmm-pm", "tools: "} {
+		if !strings.Contains(front, line) {
+			t.Errorf("subagent frontmatter lacks %q:\n%s", line, front)
+		}
+	}
+	if !claudeGenerated(body) || !strings.Contains(body, "Veteran PM who ships.") {
+		t.Errorf("subagent body:\n%s", body)
+	}
+	if !strings.Contains(body, home+"/skills/bmad-bmm-prd/SKILL.md") {
+		t.Errorf("menu does not point at the skill:\n%s", body)
+	}
+	// Workflow shortcuts are subagents too; Vibe's files are not written.
+	read("agents/bmad-bmm-prd.md")
+	for _, rel := range []string{"agents/bmad-bmm-pm.toml", "prompts", "AGENTS.md"} {
+		if _, err := os.Stat(filepath.Join(home, rel)); err == nil {
+			t.Errorf("%s written for the claude target", rel)
+		}
+	}
+
+	for _, rel := range []string{"skills/bmad-bmm-prd/SKILL.md", "skills/bmad-bmm-task-review/SKILL.md"} {
+		skill := read(rel)
+		if !strings.Contains(skill, "allowed-tools:\n  - Read\n") || strings.Contains(skill, "read_file") {
+			t.Errorf("%s: tools not mapped:\n%s", rel, skill)
+		}
+	}
+
+	// ~/.claude/CLAUDE.md applies to every project: only written on request.
+	if _, err := os.Stat(filepath.Join(home, "CLAUDE.md")); err == nil {
+		t.Error("global CLAUDE.md written without -global-index")
+	}
+}
+
+func TestClaudeIndexSection(t *testing.T) {
+	bundles, method := testSources(t)
+
+	t.Run("global opt-in", func(t *testing.T) {
+		home := filepath.Join(t.TempDir(), ".claude")
+		writeTree(t, home, map[string]string{"CLAUDE.md": "# My preferences\n\nUse tabs.\n"})
+		runTestConversion(t, claudeTarget{}, scopeGlobal, home, "", bundles, method, true)
+		data, _ := os.ReadFile(filepath.Join(home, "CLAUDE.md"))
+		got := string(data)
+		if !strings.HasPrefix(got, "# My preferences\n\nUse tabs.\n\n"+agentsMDBegin+"\n") || !strings.HasSuffix(got, agentsMDEnd+"\n") {
+			t.Errorf("CLAUDE.md:\n%s", got)
+		}
+		if !strings.Contains(got, "`@agent-bmad-bmm-pm`") {
+			t.Errorf("index lacks the agent:\n%s", got)
+		}
+	})
+
+	t.Run("project", func(t *testing.T) {
+		root := t.TempDir()
+		home := filepath.Join(root, ".claude")
+		writeTree(t, root, map[string]string{"CLAUDE.md": "Project notes.\n"})
+		runTestConversion(t, claudeTarget{}, scopeProject, home, root, bundles, method, false)
+		first, _ := os.ReadFile(filepath.Join(root, "CLAUDE.md"))
+		if !strings.HasPrefix(string(first), "Project notes.\n\n"+agentsMDBegin) {
+			t.Errorf("CLAUDE.md:\n%s", first)
+		}
+		if _, err := os.Stat(filepath.Join(home, "CLAUDE.md")); err == nil {
+			t.Error("index also written into the install dir")
+		}
+
+		report := runTestConversion(t, claudeTarget{}, scopeProject, home, root, bundles, method, false)
+		second, _ := os.ReadFile(filepath.Join(root, "CLAUDE.md"))
+		if string(second) != string(first) || len(report.updated) > 0 {
+			t.Errorf("second run changed %v:\n%s", report.updated, second)
+		}
+	})
+}
@@ -0,0 +1,557 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// --- Configuration file ---
+
+const configFileName = "bmad2vibe.toml"
+
+// fileConfig holds the settings read from bmad2vibe.toml files.
+//
+//	on_conflict = "skip"
+//
+//	[[conflict]]
+//	path = "prompts/bmad-bmm-*.md"
+//	strategy = "merge"
+//
+//	[sources]
+//	method_repo = "git@github.com:acme/BMAD-METHOD.git"
+//	method_ref = "v6.0.0"
+//
+//	[skills]
+//	inline_threshold = 65536
+//
+//	[budgets]
+//	tokenizer = "mixed"
+//	prompt = { warn = 24000, error = 96000 }
+//
+//	[output]
+//	folder = "docs"
+//	planning_artifacts = "docs/planning"
+//
+//	[variables]
+//	user_name = "Ada"
+//	communication_language = "French"
+//
+//	[diagnostics]
+//	ignore = ["BV010", "small-prompt:prompts/bmad-cis-*.md"]
+//	werror = true
+//
+//	[tools]
+//	safe = ["read_file", "grep", "list_dir", "ask_user_question"]
+//
+//	[modules.cis]
+//	safety = "safe"
+//
+//	[agents.dev]
+//	safety = "neutral"
+//	auto_approve = false
+//
+//	[[workflows]]
+//	match = "bmad-bmm-4-implementation-*"
+//	safety = "destructive"
+type fileConfig struct {
+	OnConflict string         // default strategy for hand-edited outputs
+	Conflicts  []conflictRule // per-path strategies, first match wins
+
+	Sources sourcesConfig
+	Skills  skillsConfig
+	Budgets budgetsConfig
+	Diags   diagnosticsConfig
+
+	Output    outputConfig
+	Variables map[string]string // BMAD variables, e.g. user_name; see -var
+
+	Tools     map[string][]string       // safety level → enabled tools
+	Modules   map[string]policyOverride // keyed by module
+	Agents    map[string]policyOverride // keyed by "<slug>" or "<module>/<slug>"
+	Workflows []workflowRule            // first match wins
+}
+
+// sourcesConfig selects and pins the BMAD repositories; command-line flags
+// take precedence.
+type sourcesConfig struct {
+	BundlesRepo string
+	MethodRepo  string
+	BundlesRef  string
+	MethodRef   string
+}
+
+type skillsConfig struct {
+	InlineThreshold *int // bytes; see -inline-threshold
+}
+
+// budgetsConfig sets the tokenizer and the token budgets per artifact kind
+// ("prompt", "skill").
+type budgetsConfig struct {
+	Tokenizer string
+	Limits    map[string]budgetOverride
+}
+
+type budgetOverride struct {
+	Warn  *int
+	Error *int
+}
+
+// diagnosticsConfig suppresses diagnostics and promotes warnings; -ignore
+// adds to the rules and -werror enables promotion.
+type diagnosticsConfig struct {
+	Ignore []ignoreRule
+	Werror *bool
+}
+
+// outputConfig sets where BMAD artifacts are written, relative to the project
+// root; -output-folder, -planning-artifacts and -implementation-artifacts
+// take precedence.
+type outputConfig struct {
+	Folder                  string
+	PlanningArtifacts       string
+	ImplementationArtifacts string
+}
+
+type conflictRule struct {
+	Path     string // glob relative to vibe-home; "**" crosses directories
+	Strategy string
+}
+
+// userConfigPath returns the user-level config file,
+// e.g. ~/.config/bmad2vibe/bmad2vibe.toml.
+func userConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "bmad2vibe", configFileName)
+}
+
+// loadConfig reads the user-level config and the project config and merges
+// them, project settings taking precedence. The project config is path, or
+// ./bmad2vibe.toml when path is empty. Missing default files are skipped; the
+// files actually read are returned.
+func loadConfig(path string) (*fileConfig, []string, error) {
+	merged := &fileConfig{}
+	var loaded []string
+
+	project := path
+	if project == "" {
+		project = configFileName
+	}
+	for _, f := range []struct {
+		path     string
+		required bool
+	}{
+		{userConfigPath(), false},
+		{project, path != ""},
+	} {
+		if f.path == "" {
+			continue
+		}
+		fc, err := loadConfigFile(f.path)
+		if os.IsNotExist(err) && !f.required {
+			continue
+		}
+		if err != nil {
+			return nil, loaded, err
+		}
+		merged = mergeConfig(merged, fc)
+		loaded = append(loaded, f.path)
+	}
+	return merged, loaded, nil
+}
+
+func loadConfigFile(path string) (*fileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseTOML(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	fc, err := decodeConfig(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return fc, nil
+}
+
+// mergeConfig layers over on top of base. Scalars and per-key entries of over
+// replace those of base field by field; rule lists of over are tried first.
+func mergeConfig(base, over *fileConfig) *fileConfig {
+	out := &fileConfig{
+		OnConflict: base.OnConflict,
+		Sources:    base.Sources,
+		Output:     base.Output,
+		Skills:     base.Skills,
+		Budgets:    budgetsConfig{Tokenizer: base.Budgets.Tokenizer, Limits: make(map[string]budgetOverride)},
+		Diags:      diagnosticsConfig{Ignore: append(append([]ignoreRule{}, base.Diags.Ignore...), over.Diags.Ignore...), Werror: base.Diags.Werror},
+		Conflicts:  append(append([]conflictRule{}, over.Conflicts...), base.Conflicts...),
+		Tools:      make(map[string][]string),
+		Modules:    make(map[string]policyOverride),
+		Agents:     make(map[string]policyOverride),
+		Variables:  make(map[string]string),
+		Workflows:  append(append([]workflowRule{}, over.Workflows...), base.Workflows...),
+	}
+	if over.OnConflict != "" {
+		out.OnConflict = over.OnConflict
+	}
+	if over.Sources.BundlesRepo != "" {
+		out.Sources.BundlesRepo = over.Sources.BundlesRepo
+	}
+	if over.Sources.MethodRepo != "" {
+		out.Sources.MethodRepo = over.Sources.MethodRepo
+	}
+	if over.Sources.BundlesRef != "" {
+		out.Sources.BundlesRef = over.Sources.BundlesRef
+	}
+	if over.Sources.MethodRef != "" {
+		out.Sources.MethodRef = over.Sources.MethodRef
+	}
+	if over.Output.Folder != "" {
+		out.Output.Folder = over.Output.Folder
+	}
+	if over.Output.PlanningArtifacts != "" {
+		out.Output.PlanningArtifacts = over.Output.PlanningArtifacts
+	}
+	if over.Output.ImplementationArtifacts != "" {
+		out.Output.ImplementationArtifacts = over.Output.ImplementationArtifacts
+	}
+	if over.Skills.InlineThreshold != nil {
+		out.Skills.InlineThreshold = over.Skills.InlineThreshold
+	}
+	if over.Diags.Werror != nil {
+		out.Diags.Werror = over.Diags.Werror
+	}
+	if over.Budgets.Tokenizer != "" {
+		out.Budgets.Tokenizer = over.Budgets.Tokenizer
+	}
+	for _, m := range []map[string]budgetOverride{base.Budgets.Limits, over.Budgets.Limits} {
+		for k, o := range m {
+			d := out.Budgets.Limits[k]
+			if o.Warn != nil {
+				d.Warn = o.Warn
+			}
+			if o.Error != nil {
+				d.Error = o.Error
+			}
+			out.Budgets.Limits[k] = d
+		}
+	}
+	for _, m := range []map[string][]string{base.Tools, over.Tools} {
+		for k, v := range m {
+			out.Tools[k] = v
+		}
+	}
+	for _, m := range []map[string]string{base.Variables, over.Variables} {
+		for k, v := range m {
+			out.Variables[k] = v
+		}
+	}
+	mergeOverrides(out.Modules, base.Modules, over.Modules)
+	mergeOverrides(out.Agents, base.Agents, over.Agents)
+	return out
+}
+
+func mergeOverrides(dst, base, over map[string]policyOverride) {
+	for k, v := range base {
+		dst[k] = v
+	}
+	for k, o := range over {
+		d := dst[k]
+		if o.Safety != "" {
+			d.Safety = o.Safety
+		}
+		if o.Tools != nil {
+			d.Tools = o.Tools
+		}
+		if o.AutoApprove != nil {
+			d.AutoApprove = o.AutoApprove
+		}
+		dst[k] = d
+	}
+}
+
+func decodeConfig(doc map[string]any) (*fileConfig, error) {
+	fc := &fileConfig{}
+	d := configDecoder{}
+
+	fc.OnConflict = d.str(doc, "on_conflict")
+	for i, t := range d.tables(doc, "conflict") {
+		r := conflictRule{Path: d.str(t, "path"), Strategy: d.str(t, "strategy")}
+		if r.Path == "" {
+			d.failf("conflict[%d]: missing path", i)
+		}
+		d.unknown(t, fmt.Sprintf("conflict[%d]", i), "path", "strategy")
+		fc.Conflicts = append(fc.Conflicts, r)
+	}
+
+	if src := d.table(doc, "sources"); src != nil {
+		fc.Sources.BundlesRepo = d.str(src, "bundles_repo")
+		fc.Sources.MethodRepo = d.str(src, "method_repo")
+		fc.Sources.BundlesRef = d.str(src, "bundles_ref")
+		fc.Sources.MethodRef = d.str(src, "method_ref")
+		d.unknown(src, "sources", "bundles_repo", "method_repo", "bundles_ref", "method_ref")
+	}
+
+	if sk := d.table(doc, "skills"); sk != nil {
+		fc.Skills.InlineThreshold = d.intPtr(sk, "inline_threshold")
+		if t := fc.Skills.InlineThreshold; t != nil && *t < 0 {
+			d.failf("skills.inline_threshold: must be 0 or more")
+		}
+		d.unknown(sk, "skills", "inline_threshold")
+	}
+
+	if bt := d.table(doc, "budgets"); bt != nil {
+		fc.Budgets.Tokenizer = d.str(bt, "tokenizer")
+		if t := fc.Budgets.Tokenizer; t != "" && tokenizers[t] == nil {
+			d.failf("budgets.tokenizer: unknown tokenizer %q (want %s)", t, tokenizerNames())
+		}
+		fc.Budgets.Limits = make(map[string]budgetOverride)
+		for kind := range defaultBudgets {
+			t := d.table(bt, kind)
+			if t == nil {
+				continue
+			}
+			ctx := "budgets." + kind
+			o := budgetOverride{Warn: d.intPtr(t, "warn"), Error: d.intPtr(t, "error")}
+			for _, v := range []*int{o.Warn, o.Error} {
+				if v != nil && *v < 0 {
+					d.failf("%s: budgets must be 0 or more", ctx)
+				}
+			}
+			d.unknown(t, ctx, "warn", "error")
+			fc.Budgets.Limits[kind] = o
+		}
+		d.unknown(bt, "budgets", "tokenizer", "prompt", "skill")
+	}
+
+	if dt := d.table(doc, "diagnostics"); dt != nil {
+		for i, s := range d.strs(dt, "ignore") {
+			r, err := parseIgnoreRule(s)
+			if err != nil {
+				d.failf("diagnostics.ignore[%d]: %v", i, err)
+				continue
+			}
+			fc.Diags.Ignore = append(fc.Diags.Ignore, r)
+		}
+		fc.Diags.Werror = d.boolPtr(dt, "werror")
+		d.unknown(dt, "diagnostics", "ignore", "werror")
+	}
+
+	if ot := d.table(doc, "output"); ot != nil {
+		fc.Output.Folder = d.str(ot, "folder")
+		fc.Output.PlanningArtifacts = d.str(ot, "planning_artifacts")
+		fc.Output.ImplementationArtifacts = d.str(ot, "implementation_artifacts")
+		d.unknown(ot, "output", "folder", "planning_artifacts", "implementation_artifacts")
+	}
+
+	if vt := d.table(doc, "variables"); vt != nil {
+		fc.Variables = make(map[string]string, len(vt))
+		for name := range vt {
+			fc.Variables[name] = d.str(vt, name)
+		}
+	}
+
+	if tools := d.table(doc, "tools"); tools != nil {
+		fc.Tools = make(map[string][]string)
+		for level := range tools {
+			if !validSafety[level] {
+				d.failf("tools.%s: unknown safety level", level)
+			}
+			fc.Tools[level] = d.strs(tools, level)
+		}
+	}
+	fc.Modules = d.overrides(doc, "modules")
+	fc.Agents = d.overrides(doc, "agents")
+	for i, t := range d.tables(doc, "workflows") {
+		ctx := fmt.Sprintf("workflows[%d]", i)
+		r := workflowRule{Match: d.str(t, "match"), policyOverride: d.override(t, ctx, "match")}
+		if r.Match == "" {
+			d.failf("%s: missing match", ctx)
+		}
+		fc.Workflows = append(fc.Workflows, r)
+	}
+
+	d.unknown(doc, "", "on_conflict", "conflict", "sources", "skills", "budgets", "diagnostics", "output", "variables", "tools", "modules", "agents", "workflows")
+
+	if fc.OnConflict != "" && !validConflictStrategy(fc.OnConflict) {
+		d.failf("on_conflict: unknown strategy %q", fc.OnConflict)
+	}
+	for i, r := range fc.Conflicts {
+		if !validConflictStrategy(r.Strategy) {
+			d.failf("conflict[%d]: unknown strategy %q", i, r.Strategy)
+		}
+	}
+	return fc, d.err
+}
+
+// configDecoder extracts typed values from a parsed TOML document, keeping
+// the first error so callers can decode every field before checking.
+type configDecoder struct {
+	err error
+}
+
+func (d *configDecoder) failf(format string, a ...any) {
+	if d.err == nil {
+		d.err = fmt.Errorf(format, a...)
+	}
+}
+
+func (d *configDecoder) str(m map[string]any, key string) string {
+	v, ok := m[key]
+	if !ok {
+		return ""
+	}
+	s, ok := v.(string)
+	if !ok {
+		d.failf("%s: expected a string, got %T", key, v)
+	}
+	return s
+}
+
+func (d *configDecoder) strs(m map[string]any, key string) []string {
+	v, ok := m[key]
+	if !ok {
+		return nil
+	}
+	list, ok := v.([]any)
+	if !ok {
+		d.failf("%s: expected an array of strings, got %T", key, v)
+		return nil
+	}
+	out := []string{}
+	for i, e := range list {
+		s, ok := e.(string)
+		if !ok {
+			d.failf("%s[%d]: expected a string, got %T", key, i, e)
+			continue
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func (d *configDecoder) boolPtr(m map[string]any, key string) *bool {
+	v, ok := m[key]
+	if !ok {
+		return nil
+	}
+	b, ok := v.(bool)
+	if !ok {
+		d.failf("%s: expected a boolean, got %T", key, v)
+		return nil
+	}
+	return &b
+}
+
+func (d *configDecoder) intPtr(m map[string]any, key string) *int {
+	v, ok := m[key]
+	if !ok {
+		return nil
+	}
+	n, ok := v.(int64)
+	if !ok {
+		d.failf("%s: expected an integer, got %T", key, v)
+		return nil
+	}
+	i := int(n)
+	return &i
+}
+
+// table returns the table stored under key, or nil.
+func (d *configDecoder) table(m map[string]any, key string) map[string]any {
+	v, ok := m[key]
+	if !ok {
+		return nil
+	}
+	t, ok := v.(map[string]any)
+	if !ok {
+		d.failf("%s: expected a table", key)
+	}
+	return t
+}
+
+// overrides decodes a table of policy overrides such as [agents.<name>].
+func (d *configDecoder) overrides(m map[string]any, key string) map[string]policyOverride {
+	t := d.table(m, key)
+	if t == nil {
+		return nil
+	}
+	out := make(map[string]policyOverride, len(t))
+	for name := range t {
+		ctx := key + "." + name
+		sub := d.table(t, name)
+		if sub == nil {
+			d.failf("%s: expected a table", ctx)
+			continue
+		}
+		out[name] = d.override(sub, ctx)
+	}
+	return out
+}
+
+// override decodes safety, enabled_tools and auto_approve from t. extra lists
+// additional keys allowed in t.
+func (d *configDecoder) override(t map[string]any, ctx string, extra ...string) policyOverride {
+	o := policyOverride{
+		Safety:      d.str(t, "safety"),
+		Tools:       d.strs(t, "enabled_tools"),
+		AutoApprove: d.boolPtr(t, "auto_approve"),
+	}
+	if o.Safety != "" && !validSafety[o.Safety] {
+		d.failf("%s: invalid safety %q (want safe, neutral, destructive or yolo)", ctx, o.Safety)
+	}
+	d.unknown(t, ctx, append([]string{"safety", "enabled_tools", "auto_approve"}, extra...)...)
+	return o
+}
+
+// tables returns the array of tables stored under key.
+func (d *configDecoder) tables(m map[string]any, key string) []map[string]any {
+	v, ok := m[key]
+	if !ok {
+		return nil
+	}
+	list, ok := v.([]any)
+	if !ok {
+		d.failf("%s: expected an array of tables ([[%s]])", key, key)
+		return nil
+	}
+	var out []map[string]any
+	for i, e := range list {
+		t, ok := e.(map[string]any)
+		if !ok {
+			d.failf("%s[%d]: expected a table", key, i)
+			continue
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// unknown flags keys of m that are not in known.
+func (d *configDecoder) unknown(m map[string]any, ctx string, known ...string) {
+	allowed := make(map[string]bool, len(known))
+	for _, k := range known {
+		allowed[k] = true
+	}
+	var extra []string
+	for k := range m {
+		if !allowed[k] {
+			extra = append(extra, k)
+		}
+	}
+	if len(extra) == 0 {
+		return
+	}
+	sort.Strings(extra)
+	if ctx != "" {
+		ctx += ": "
+	}
+	d.failf("%sunknown key(s) %s", ctx, strings.Join(extra, ", "))
+}
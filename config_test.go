@@ -0,0 +1,123 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "xdg"))
+	t.Setenv("HOME", dir)
+	user := userConfigPath()
+	project := filepath.Join(dir, "project", configFileName)
+	writeTree(t, dir, map[string]string{
+		"xdg/bmad2vibe/" + configFileName: `on_conflict = "merge"
+
+[[conflict]]
+path = "prompts/*"
+strategy = "force"
+
+[sources]
+bundles_ref = "u1"
+method_ref = "v5"
+
+[variables]
+user_name = "Ada"
+communication_language = "English"
+
+[agents.dev]
+safety = "safe"
+auto_approve = true
+`,
+		"project/" + configFileName: `on_conflict = "new"
+
+[[conflict]]
+path = "prompts/bmad-bmm-*.md"
+strategy = "merge"
+
+[sources]
+method_ref = "v6"
+
+[variables]
+user_name = "Bob"
+
+[agents.dev]
+safety = "neutral"
+`,
+	})
+
+	fc, loaded, err := loadConfig(project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, []string{user, project}) {
+		t.Errorf("loaded %v, want the user then the project config", loaded)
+	}
+
+	// The project overrides the user config key by key.
+	if fc.OnConflict != "new" || fc.Sources.MethodRef != "v6" || fc.Sources.BundlesRef != "u1" {
+		t.Errorf("on_conflict %q, sources %+v", fc.OnConflict, fc.Sources)
+	}
+	if want := map[string]string{"user_name": "Bob", "communication_language": "English"}; !reflect.DeepEqual(fc.Variables, want) {
+		t.Errorf("variables = %v, want %v", fc.Variables, want)
+	}
+	if dev := fc.Agents["dev"]; dev.Safety != "neutral" || dev.AutoApprove == nil || !*dev.AutoApprove {
+		t.Errorf("agents.dev = %+v", dev)
+	}
+	if len(fc.Conflicts) != 2 || fc.Conflicts[0].Strategy != "merge" {
+		t.Errorf("conflict rules = %+v, want the project's first", fc.Conflicts)
+	}
+
+	// Flags override both files; matching [[conflict]] rules stay first.
+	cfg := &config{file: fc, onConflict: conflictForce, varOverrides: varFlags{"user_name": "Cy"}, method: sourceRepo{Dir: dir}}
+	for rel, want := range map[string]string{
+		"agents/bmad-bmm-dev.toml": conflictForce,
+		"prompts/bmad-bmm-pm.md":   conflictMerge,
+		"prompts/bmad-cis-x.md":    conflictForce,
+	} {
+		if got := cfg.conflictStrategy(rel); got != want {
+			t.Errorf("conflictStrategy(%s) = %q, want %q", rel, got, want)
+		}
+	}
+	vars := cfg.moduleVars("bmm")
+	if vars["user_name"] != "Cy" || vars["communication_language"] != "English" {
+		t.Errorf("user_name %q, communication_language %q", vars["user_name"], vars["communication_language"])
+	}
+
+	// Without an explicit path, a missing ./bmad2vibe.toml is not an error.
+	t.Chdir(dir)
+	if _, loaded, err := loadConfig(""); err != nil || len(loaded) != 1 {
+		t.Errorf("default project config: loaded %v, err %v", loaded, err)
+	}
+	if _, _, err := loadConfig(filepath.Join(dir, "missing.toml")); !os.IsNotExist(err) {
+		t.Errorf("missing -config file: err = %v", err)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	tests := []struct {
+		name, src, err string
+	}{
+		{"unknown top-level key", `on_conflct = "merge"`, "unknown key(s) on_conflct"},
+		{"unknown table key", "[sources]\nmethod_reff = \"v6\"\n", "sources: unknown key(s) method_reff"},
+		{"unknown rule key", "[[conflict]]\npath = \"x\"\nstrategy = \"skip\"\nmode = \"y\"\n", "conflict[0]: unknown key(s) mode"},
+		{"wrong type", "[skills]\ninline_threshold = \"big\"\n", "inline_threshold"},
+		{"bad strategy", `on_conflict = "overwrite"`, `unknown strategy "overwrite"`},
+		{"bad TOML", "on_conflict = \n", "line 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), configFileName)
+			os.WriteFile(path, []byte(tt.src), 0o644)
+			_, _, err := loadConfig(path)
+			if err == nil || !strings.Contains(err.Error(), tt.err) || !strings.HasPrefix(err.Error(), path+": ") {
+				t.Errorf("err = %v, want %q in %s", err, tt.err, path)
+			}
+		})
+	}
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// --- Hand-edited output protection ---
+
+// Strategies applied when a generated file was edited since bmad2vibe last
+// wrote it.
+const (
+	conflictSkip  = "skip"  // keep the edited file, warn
+	conflictNew   = "new"   // keep the edited file, write <file>.new next to it
+	conflictMerge = "merge" // three-way merge of the edits into the new output
+	conflictForce = "force" // overwrite
+)
+
+func validConflictStrategy(s string) bool {
+	switch s {
+	case conflictSkip, conflictNew, conflictMerge, conflictForce:
+		return true
+	}
+	return false
+}
+
+// baseDir stores a copy of every generated file as last written, used as the
+// common ancestor for merges.
+const baseDir = ".bmad2vibe/base"
+
+func (cfg *config) basePath(rel string) string {
+	return filepath.Join(cfg.vibeHome, filepath.FromSlash(baseDir), filepath.FromSlash(rel))
+}
+
+func (cfg *config) saveBase(rel, content string) {
+	p := cfg.basePath(rel)
+	os.MkdirAll(filepath.Dir(p), 0o755)
+	os.WriteFile(p, []byte(content), 0o644)
+}
+
+func (cfg *config) removeBase(rel string) {
+	p := cfg.basePath(rel)
+	if os.Remove(p) == nil {
+		removeEmptyParents(filepath.Dir(p), cfg.vibeHome)
+	}
+}
+
+// editedSinceGeneration reports whether the file at rel, currently hashing to
+// disk, holds content bmad2vibe did not write. Without any previous manifest
+// (first run, or an install predating it) files are assumed to be ours.
+func (cfg *config) editedSinceGeneration(rel, disk string) bool {
+	if prev, ok := cfg.prevManifest.Files[rel]; ok {
+		return disk != prev.Hash
+	}
+	return len(cfg.prevManifest.Files) > 0
+}
+
+// conflictStrategy returns the strategy for rel: the first matching
+// [[conflict]] rule, then -on-conflict, then the config default, then skip.
+func (cfg *config) conflictStrategy(rel string) string {
+	for _, r := range cfg.file.Conflicts {
+		if matchGlob(r.Path, rel) {
+			return r.Strategy
+		}
+	}
+	if cfg.onConflict != "" {
+		return cfg.onConflict
+	}
+	if cfg.file.OnConflict != "" {
+		return cfg.file.OnConflict
+	}
+	return conflictSkip
+}
+
+// resolveConflict writes content for a path whose file was edited by hand,
+// according to the configured strategy.
+func resolveConflict(cfg *config, path, content string, entry manifestEntry, src source, report *conversionReport) {
+	rel := cfg.manifestPath(path)
+	strategy := cfg.conflictStrategy(rel)
+	report.conflicts = append(report.conflicts, rel)
+
+	// Until the new content is applied, the previous generation stays the
+	// reference for edit detection and merges.
+	keepPrevious := func() {
+		if prev, ok := cfg.prevManifest.Files[rel]; ok {
+			cfg.manifest.Files[rel] = prev
+		}
+	}
+
+	if cfg.dryRun {
+		cfg.printf("   [DRY] %s (edited, %s)\n", path, strategy)
+		keepPrevious()
+		return
+	}
+
+	switch strategy {
+	case conflictForce:
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			report.err(diagIO, rel, "write: %v", err)
+			keepPrevious()
+			return
+		}
+		cfg.manifest.Files[rel] = entry
+		cfg.saveBase(rel, content)
+		report.warn(diagEditedOutput, rel, "edited since generation — overwritten")
+
+	case conflictNew:
+		keepPrevious()
+		writeFile(cfg, path+".new", content, src, report)
+		report.warn(diagEditedOutput, rel, "edited since generation — new version written to %s.new", filepath.Base(path))
+
+	case conflictMerge:
+		base, err := os.ReadFile(cfg.basePath(rel))
+		ours, err2 := os.ReadFile(path)
+		if err != nil || err2 != nil {
+			keepPrevious()
+			writeFile(cfg, path+".new", content, src, report)
+			report.warn(diagEditedOutput, rel, "edited since generation but no merge base is available — new version written to %s.new", filepath.Base(path))
+			return
+		}
+		merged, clean := merge3(string(base), string(ours), content)
+		if strings.HasSuffix(path, ".toml") && !validTOML(merged) {
+			// Vibe cannot load an agent file holding conflict markers.
+			keepPrevious()
+			writeFile(cfg, path+".new", content, src, report)
+			report.warn(diagMergeConflict, rel, "merge conflicts with local edits — new version written to %s.new", filepath.Base(path))
+			return
+		}
+		if merged != string(ours) {
+			if err := os.WriteFile(path, []byte(merged), 0o644); err != nil {
+				report.err(diagIO, rel, "write: %v", err)
+				keepPrevious()
+				return
+			}
+		}
+		cfg.manifest.Files[rel] = entry
+		cfg.saveBase(rel, content)
+		if !clean {
+			report.warn(diagMergeConflict, rel, "merge conflicts with local edits — resolve the <<<<<<< markers")
+		} else if cfg.verbose {
+			cfg.printf("   🔀 %s: local edits merged\n", rel)
+		}
+
+	default: // skip
+		keepPrevious()
+		report.warn(diagEditedOutput, rel, "edited since generation — kept, new version not applied")
+	}
+}
+
+// --- Three-way merge ---
+
+// merge3 merges the changes from base to ours and from base to theirs, line
+// by line. Overlapping changes that differ are emitted between conflict
+// markers and reported through clean=false.
+func merge3(base, ours, theirs string) (merged string, clean bool) {
+	b, o, t := splitLines(base), splitLines(ours), splitLines(theirs)
+	mo := matchIndex(b, o)
+	mt := matchIndex(b, t)
+
+	var out []string
+	clean = true
+	i, io, it := 0, 0, 0
+	for i < len(b) || io < len(o) || it < len(t) {
+		// Next base line kept by both sides.
+		k := i
+		for k < len(b) && (mo[k] < 0 || mt[k] < 0) {
+			k++
+		}
+		if k == i && k < len(b) && mo[k] == io && mt[k] == it {
+			out = append(out, b[k])
+			i, io, it = k+1, io+1, it+1
+			continue
+		}
+
+		endO, endT := len(o), len(t)
+		if k < len(b) {
+			endO, endT = mo[k], mt[k]
+		}
+		cb, co, ct := b[i:k], o[io:endO], t[it:endT]
+		switch {
+		case equalLines(co, cb):
+			out = append(out, ct...)
+		case equalLines(ct, cb), equalLines(co, ct):
+			out = append(out, co...)
+		default:
+			clean = false
+			out = append(out, "<<<<<<< local edits\n")
+			out = append(out, terminated(co)...)
+			out = append(out, "||||||| previous bmad2vibe output\n")
+			out = append(out, terminated(cb)...)
+			out = append(out, "=======\n")
+			out = append(out, terminated(ct)...)
+			out = append(out, ">>>>>>> new bmad2vibe output\n")
+		}
+		i, io, it = k, endO, endT
+	}
+	return strings.Join(out, ""), clean
+}
+
+// validTOML reports whether a merged agent file still parses.
+func validTOML(s string) bool {
+	_, err := parseTOML(s)
+	return err == nil
+}
+
+// splitLines splits s into lines that keep their trailing newline.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// terminated ensures the last line of a conflict section ends with a newline
+// so the marker that follows starts on its own line.
+func terminated(lines []string) []string {
+	if n := len(lines); n > 0 && !strings.HasSuffix(lines[n-1], "\n") {
+		lines = append(lines[:n-1:n-1], lines[n-1]+"\n")
+	}
+	return lines
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// matchIndex returns, for each line of a, the index of the matching line in
+// b or -1. Matches are strictly increasing in both sequences.
+func matchIndex(a, b []string) []int {
+	m := make([]int, len(a))
+	for i := range m {
+		m[i] = -1
+	}
+	patienceMatch(a, b, 0, len(a), 0, len(b), m)
+	return m
+}
+
+// patienceMatch matches a[alo:ahi] against b[blo:bhi] using patience diff:
+// common prefix and suffix, then lines unique on both sides anchored through
+// a longest increasing subsequence, recursing between anchors.
+func patienceMatch(a, b []string, alo, ahi, blo, bhi int, m []int) {
+	for alo < ahi && blo < bhi && a[alo] == b[blo] {
+		m[alo] = blo
+		alo, blo = alo+1, blo+1
+	}
+	for alo < ahi && blo < bhi && a[ahi-1] == b[bhi-1] {
+		ahi, bhi = ahi-1, bhi-1
+		m[ahi] = bhi
+	}
+	if alo == ahi || blo == bhi {
+		return
+	}
+
+	type occ struct{ countA, countB, posA, posB int }
+	seen := make(map[string]*occ)
+	for i := alo; i < ahi; i++ {
+		o := seen[a[i]]
+		if o == nil {
+			o = &occ{}
+			seen[a[i]] = o
+		}
+		o.countA++
+		o.posA = i
+	}
+	for j := blo; j < bhi; j++ {
+		if o := seen[b[j]]; o != nil {
+			o.countB++
+			o.posB = j
+		}
+	}
+	type pair struct{ a, b int }
+	var uniq []pair
+	for _, o := range seen {
+		if o.countA == 1 && o.countB == 1 {
+			uniq = append(uniq, pair{o.posA, o.posB})
+		}
+	}
+	if len(uniq) == 0 {
+		return
+	}
+	sort.Slice(uniq, func(i, j int) bool { return uniq[i].a < uniq[j].a })
+
+	// Longest increasing subsequence on b positions.
+	tails := []int{}
+	prev := make([]int, len(uniq))
+	for i, p := range uniq {
+		k := sort.Search(len(tails), func(x int) bool { return uniq[tails[x]].b >= p.b })
+		if k > 0 {
+			prev[i] = tails[k-1]
+		} else {
+			prev[i] = -1
+		}
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+	anchors := make([]pair, len(tails))
+	for i, k := len(tails)-1, tails[len(tails)-1]; i >= 0; i, k = i-1, prev[k] {
+		anchors[i] = uniq[k]
+	}
+
+	pa, pb := alo, blo
+	for _, an := range anchors {
+		patienceMatch(a, b, pa, an.a, pb, an.b, m)
+		m[an.a] = an.b
+		pa, pb = an.a+1, an.b+1
+	}
+	patienceMatch(a, b, pa, ahi, pb, bhi, m)
+}
+
+// --- Globs ---
+
+var globCache = map[string]*regexp.Regexp{}
+
+// matchGlob matches a slash-separated name against pattern, where "*" and "?"
+// stay within a path segment and "**" matches across segments.
+func matchGlob(pattern, name string) bool {
+	re, ok := globCache[pattern]
+	if !ok {
+		var b strings.Builder
+		b.WriteString("^")
+		for i := 0; i < len(pattern); i++ {
+			switch c := pattern[i]; c {
+			case '*':
+				if i+1 < len(pattern) && pattern[i+1] == '*' {
+					i++
+					if i+1 < len(pattern) && pattern[i+1] == '/' {
+						i++
+						b.WriteString("(?:.*/)?")
+					} else {
+						b.WriteString(".*")
+					}
+				} else {
+					b.WriteString("[^/]*")
+				}
+			case '?':
+				b.WriteString("[^/]")
+			default:
+				b.WriteString(regexp.QuoteMeta(string(c)))
+			}
+		}
+		b.WriteString("$")
+		re = regexp.MustCompile(b.String())
+		globCache[pattern] = re
+	}
+	return re.MatchString(name)
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMerge3(t *testing.T) {
+	tests := []struct {
+		name               string
+		base, ours, theirs string
+		want               string
+		clean              bool
+	}{
+		{
+			name:   "no changes",
+			base:   "a\nb\nc\n",
+			ours:   "a\nb\nc\n",
+			theirs: "a\nb\nc\n",
+			want:   "a\nb\nc\n",
+			clean:  true,
+		},
+		{
+			name:   "edits on both sides in separate places",
+			base:   "a\nb\nc\nd\ne\n",
+			ours:   "a\nB (local)\nc\nd\ne\n",
+			theirs: "a\nb\nc\nd\nE (new)\n",
+			want:   "a\nB (local)\nc\nd\nE (new)\n",
+			clean:  true,
+		},
+		{
+			name:   "same edit on both sides",
+			base:   "a\nb\nc\n",
+			ours:   "a\nx\nc\n",
+			theirs: "a\nx\nc\n",
+			want:   "a\nx\nc\n",
+			clean:  true,
+		},
+		{
+			name:   "local deletion",
+			base:   "a\nb\nc\n",
+			ours:   "a\nc\n",
+			theirs: "a\nb\nc\nd\n",
+			want:   "a\nc\nd\n",
+			clean:  true,
+		},
+		{
+			name:   "insertion at EOF on both sides",
+			base:   "a\nb\n",
+			ours:   "a\nb\nlocal\n",
+			theirs: "a\nb\nnew\n",
+			want: "a\nb\n" +
+				"<<<<<<< local edits\nlocal\n" +
+				"||||||| previous bmad2vibe output\n" +
+				"=======\nnew\n" +
+				">>>>>>> new bmad2vibe output\n",
+		},
+		{
+			name:   "local insertion at EOF",
+			base:   "a\nb\nc\n",
+			ours:   "a\nb\nc\nlocal\n",
+			theirs: "A\nb\nc\n",
+			want:   "A\nb\nc\nlocal\n",
+			clean:  true,
+		},
+		{
+			name:   "new insertion at EOF",
+			base:   "a\nb\nc\n",
+			ours:   "A\nb\nc\n",
+			theirs: "a\nb\nc\nnew\n",
+			want:   "A\nb\nc\nnew\n",
+			clean:  true,
+		},
+		{
+			name:   "missing final newline",
+			base:   "a\nb\nc",
+			ours:   "A\nb\nc",
+			theirs: "a\nb\nC",
+			want:   "A\nb\nC",
+			clean:  true,
+		},
+		{
+			name:   "adjacent edits conflict",
+			base:   "a\nb\n",
+			ours:   "a\nb\nlocal\n",
+			theirs: "a\nB\n",
+			want: "a\n" +
+				"<<<<<<< local edits\nb\nlocal\n" +
+				"||||||| previous bmad2vibe output\nb\n" +
+				"=======\nB\n" +
+				">>>>>>> new bmad2vibe output\n",
+		},
+		{
+			name:   "overlapping edits",
+			base:   "a\nb\nc\n",
+			ours:   "a\nours\nc\n",
+			theirs: "a\ntheirs\nc\n",
+			want: "a\n" +
+				"<<<<<<< local edits\nours\n" +
+				"||||||| previous bmad2vibe output\nb\n" +
+				"=======\ntheirs\n" +
+				">>>>>>> new bmad2vibe output\n" +
+				"c\n",
+		},
+		{
+			name:   "overlap without trailing newline",
+			base:   "a\nb",
+			ours:   "a\nours",
+			theirs: "a\ntheirs",
+			want: "a\n" +
+				"<<<<<<< local edits\nours\n" +
+				"||||||| previous bmad2vibe output\nb\n" +
+				"=======\ntheirs\n" +
+				">>>>>>> new bmad2vibe output\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, clean := merge3(tt.base, tt.ours, tt.theirs)
+			if got != tt.want || clean != tt.clean {
+				t.Errorf("merge3 = (%q, %t), want (%q, %t)", got, clean, tt.want, tt.clean)
+			}
+		})
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"prompts/*.md", "prompts/bmad-bmm-pm.md", true},
+		{"prompts/*.md", "prompts/sub/bmad-bmm-pm.md", false},
+		{"prompts/**", "prompts/sub/bmad-bmm-pm.md", true},
+		{"**/SKILL.md", "SKILL.md", true},
+		{"**/SKILL.md", "skills/bmad-core-brainstorming/SKILL.md", true},
+		{"skills/**/SKILL.md", "skills/a/b/SKILL.md", true},
+		{"agents/bmad-?m.toml", "agents/bmad-pm.toml", true},
+		{"agents/bmad-?m.toml", "agents/bmad-/m.toml", false},
+		{"agents/*.toml", "agents/pm.toml.new", false},
+		{"agents/bmad.pm", "agents/bmadxpm", false},
+		{"agents/[ab].toml", "agents/[ab].toml", true},
+		{"*", "AGENTS.md", true},
+		{"*", "agents/pm.toml", false},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %t, want %t", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestResolveConflictMerge(t *testing.T) {
+	base := "display_name = \"PM\"\nsafety = \"safe\"\ndescription = \"old\"\n"
+	ours := "display_name = \"My PM\"\nsafety = \"safe\"\ndescription = \"old\"\n"
+	tests := []struct {
+		name, rel, theirs string
+		wantDisk, wantNew string // wantNew empty: no sidecar
+	}{
+		{
+			name:     "clean merge",
+			rel:      "agents/bmad-bmm-pm.toml",
+			theirs:   "display_name = \"PM\"\nsafety = \"safe\"\ndescription = \"new\"\n",
+			wantDisk: "display_name = \"My PM\"\nsafety = \"safe\"\ndescription = \"new\"\n",
+		},
+		{
+			name:     "conflicting TOML goes to a sidecar",
+			rel:      "agents/bmad-bmm-pm.toml",
+			theirs:   "display_name = \"Product Manager\"\nsafety = \"safe\"\ndescription = \"old\"\n",
+			wantDisk: ours,
+			wantNew:  "display_name = \"Product Manager\"\nsafety = \"safe\"\ndescription = \"old\"\n",
+		},
+		{
+			name:   "conflicting markdown gets markers",
+			rel:    "prompts/bmad-bmm-pm.md",
+			theirs: "display_name = \"Product Manager\"\nsafety = \"safe\"\ndescription = \"old\"\n",
+			wantDisk: "<<<<<<< local edits\ndisplay_name = \"My PM\"\n" +
+				"||||||| previous bmad2vibe output\ndisplay_name = \"PM\"\n" +
+				"=======\ndisplay_name = \"Product Manager\"\n" +
+				">>>>>>> new bmad2vibe output\n" +
+				"safety = \"safe\"\ndescription = \"old\"\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config{vibeHome: t.TempDir(), onConflict: conflictMerge, file: &fileConfig{}}
+			path := filepath.Join(cfg.vibeHome, filepath.FromSlash(tt.rel))
+			os.MkdirAll(filepath.Dir(path), 0o755)
+			os.WriteFile(path, []byte(ours), 0o644)
+			cfg.saveBase(tt.rel, base)
+			cfg.prevManifest, cfg.manifest = newManifest(), newManifest()
+			cfg.prevManifest.Files[tt.rel] = manifestEntry{Hash: hashContent([]byte(base))}
+
+			report := &conversionReport{}
+			writeFile(cfg, path, tt.theirs, source{}, report)
+
+			if got, _ := os.ReadFile(path); string(got) != tt.wantDisk {
+				t.Errorf("file =\n%s\nwant\n%s", got, tt.wantDisk)
+			}
+			got, err := os.ReadFile(path + ".new")
+			if tt.wantNew == "" {
+				if err == nil {
+					t.Errorf("unexpected %s.new", tt.rel)
+				}
+			} else if string(got) != tt.wantNew {
+				t.Errorf("%s.new =\n%s\nwant\n%s", tt.rel, got, tt.wantNew)
+			}
+			if len(report.errors) > 0 {
+				t.Errorf("errors: %v", report.errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// --- Diagnostics ---
+//
+// Every warning and error carries a stable code so that reports can be
+// filtered, and specific issues suppressed, without matching on messages.
+// Codes are never renumbered or reused; retired codes stay reserved.
+
+// diagCode identifies a kind of diagnostic.
+type diagCode struct {
+	ID   string // BVnnn
+	Name string // kebab-case alias, accepted wherever the ID is
+}
+
+var (
+	// Generated agents and prompts (validation).
+	diagMissingPrompt   = diagCode{"BV001", "missing-prompt"}
+	diagMissingPromptID = diagCode{"BV002", "missing-prompt-id"}
+	diagMissingField    = diagCode{"BV003", "missing-field"}
+	diagInvalidSafety   = diagCode{"BV004", "invalid-safety"}
+	diagMissingSkill    = diagCode{"BV005", "missing-skill"}
+	diagMissingSkillMD  = diagCode{"BV006", "missing-skill-md"}
+	diagInvalidTOML     = diagCode{"BV007", "invalid-toml"}
+	diagInvalidField    = diagCode{"BV008", "invalid-field"}
+	diagUnknownKey      = diagCode{"BV009", "unknown-key"}
+	diagOrphanPrompt    = diagCode{"BV010", "orphan-prompt"}
+	diagSmallPrompt     = diagCode{"BV011", "small-prompt"}
+	diagUnknownTool     = diagCode{"BV012", "unknown-tool"}
+
+	// Generated skills (validation).
+	diagInvalidFrontmatter      = diagCode{"BV013", "invalid-frontmatter"}
+	diagInvalidSkillName        = diagCode{"BV014", "invalid-skill-name"}
+	diagInvalidSkillDescription = diagCode{"BV015", "invalid-skill-description"}
+	diagMissingReference        = diagCode{"BV016", "missing-reference"}
+
+	// BMAD sources.
+	diagAgentCompile   = diagCode{"BV020", "agent-compile"}
+	diagAgentParse     = diagCode{"BV021", "agent-parse"}
+	diagUnresolvedMenu = diagCode{"BV022", "unresolved-menu-item"}
+	diagWorkflowParse  = diagCode{"BV030", "workflow-parse"}
+	diagWorkflowFile   = diagCode{"BV031", "workflow-file-missing"}
+	diagNoDescription  = diagCode{"BV032", "no-description"}
+
+	diagUnresolvedPlaceholder = diagCode{"BV033", "unresolved-placeholder"}
+	diagUnconvertedReference  = diagCode{"BV034", "unconverted-reference"}
+	diagLongDescription       = diagCode{"BV035", "long-description"}
+
+	// Sizes.
+	diagTokenBudget = diagCode{"BV040", "token-budget"}
+
+	// Outputs on disk.
+	diagEditedOutput    = diagCode{"BV050", "edited-output"}
+	diagMergeConflict   = diagCode{"BV051", "merge-conflict"}
+	diagInvalidManifest = diagCode{"BV060", "invalid-manifest"}
+	diagIO              = diagCode{"BV090", "io-error"}
+)
+
+// diagCodes lists every code, for -ignore validation.
+var diagCodes = []diagCode{
+	diagMissingPrompt, diagMissingPromptID, diagMissingField, diagInvalidSafety,
+	diagMissingSkill, diagMissingSkillMD, diagInvalidTOML, diagInvalidField, diagUnknownKey,
+	diagOrphanPrompt, diagSmallPrompt, diagUnknownTool,
+	diagInvalidFrontmatter, diagInvalidSkillName, diagInvalidSkillDescription, diagMissingReference,
+	diagAgentCompile, diagAgentParse, diagUnresolvedMenu,
+	diagWorkflowParse, diagWorkflowFile, diagNoDescription, diagUnresolvedPlaceholder,
+	diagUnconvertedReference, diagLongDescription,
+	diagTokenBudget, diagEditedOutput, diagMergeConflict, diagInvalidManifest, diagIO,
+}
+
+// diagnostic is one warning or error of the run.
+type diagnostic struct {
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+	Severity string `json:"severity"`       // "warning" or "error"
+	File     string `json:"file,omitempty"` // relative to vibe-home or to its source repository
+	Line     int    `json:"line,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (d diagnostic) String() string {
+	loc := d.File
+	if loc != "" && d.Line > 0 {
+		loc = fmt.Sprintf("%s:%d", loc, d.Line)
+	}
+	if loc != "" {
+		return fmt.Sprintf("[%s] %s: %s", d.Code, loc, d.Message)
+	}
+	return fmt.Sprintf("[%s] %s", d.Code, d.Message)
+}
+
+// ignoreRule suppresses diagnostics by code or name, optionally only for
+// files matching a glob: "BV010", "orphan-prompt", "BV011:prompts/bmad-cis-*.md".
+type ignoreRule struct {
+	Code string // ID, resolved from a name if needed
+	Path string // optional glob relative to vibe-home or the source repository
+}
+
+// parseIgnoreRule parses one -ignore entry or [diagnostics] ignore item.
+func parseIgnoreRule(s string) (ignoreRule, error) {
+	code, glob, _ := strings.Cut(strings.TrimSpace(s), ":")
+	for _, c := range diagCodes {
+		if strings.EqualFold(code, c.ID) || code == c.Name {
+			return ignoreRule{Code: c.ID, Path: glob}, nil
+		}
+	}
+	return ignoreRule{}, fmt.Errorf("unknown diagnostic %q (want a code such as BV010 or a name such as orphan-prompt)", code)
+}
+
+func (r ignoreRule) matches(d diagnostic) bool {
+	return r.Code == d.Code && (r.Path == "" || matchGlob(r.Path, d.File))
+}
+
+// diagPolicy decides what happens to diagnostics as they are recorded.
+type diagPolicy struct {
+	ignore []ignoreRule
+	werror bool // report warnings as errors
+}
+
+// add records d, unless suppressed.
+func (r *conversionReport) add(d diagnostic) {
+	for _, rule := range r.policy.ignore {
+		if rule.matches(d) {
+			r.suppressed = append(r.suppressed, d)
+			return
+		}
+	}
+	if d.Severity == "warning" && r.policy.werror {
+		d.Severity = "error"
+	}
+	if d.Severity == "error" {
+		r.errors = append(r.errors, d)
+	} else {
+		r.warnings = append(r.warnings, d)
+	}
+}
+
+// warn and err record a diagnostic about file (empty when the issue is not
+// tied to one file).
+func (r *conversionReport) warn(code diagCode, file, format string, a ...any) {
+	r.warnAt(code, file, 0, format, a...)
+}
+
+func (r *conversionReport) err(code diagCode, file, format string, a ...any) {
+	r.errAt(code, file, 0, format, a...)
+}
+
+// warnAt and errAt record a diagnostic about a line of file (1-based, 0 when
+// unknown).
+func (r *conversionReport) warnAt(code diagCode, file string, line int, format string, a ...any) {
+	r.add(diagnostic{Code: code.ID, Name: code.Name, Severity: "warning", File: file, Line: line, Message: fmt.Sprintf(format, a...)})
+}
+
+func (r *conversionReport) errAt(code diagCode, file string, line int, format string, a ...any) {
+	r.add(diagnostic{Code: code.ID, Name: code.Name, Severity: "error", File: file, Line: line, Message: fmt.Sprintf(format, a...)})
+}
+
+// ignoredCodes summarizes the suppressed diagnostics, e.g. "BV010 ×3".
+func (r *conversionReport) ignoredCodes() string {
+	counts := make(map[string]int)
+	for _, d := range r.suppressed {
+		counts[d.Code]++
+	}
+	var out []string
+	for _, c := range sortedKeys(counts) {
+		out = append(out, fmt.Sprintf("%s ×%d", c, counts[c]))
+	}
+	return strings.Join(out, ", ")
+}
@@ -7,16 +7,56 @@
 //	BMAD Workflow    → Vibe Skill (SKILL.md) + inlined steps, referenced from agent prompts
 //	BMAD Task/Tool   → Vibe Skill (SKILL.md), user-invocable
 //
+// With -target claude, agents become Claude Code subagents (agents/*.md) and
+// tool names are mapped (read_file → Read, bash → Bash, ...).
+//
 // Usage:
 //
 //	bmad2vibe [flags]
-//	  -vibe-home    string  Vibe home directory (default ~/.vibe)
+//	  -vibe-home    string  Vibe home directory (default ~/.vibe, or ./.vibe with -scope project)
+//	  -scope        string  global (~/.vibe) or project (./.vibe + ./AGENTS.md) (default global)
+//	  -target       string  Comma-separated tools to install for: vibe, claude (default vibe)
+//	  -claude-home  string  Claude Code directory (default ~/.claude, or ./.claude with -scope project)
+//	  -global-index         With -scope global, also add the BMAD section to ~/.claude/CLAUDE.md
 //	  -modules      string  Comma-separated modules to convert (default "bmm,cis,bmgd")
 //	  -dry-run              Show what would be done
 //	  -verbose              Verbose output
 //	  -cleanup              Remove temp repos after conversion (default true)
 //	  -bundles-dir  string  Use local bmad-bundles instead of cloning
 //	  -method-dir   string  Use local BMAD-METHOD instead of cloning
+//	  -bundles-repo string  Git URL of bmad-bundles (https, ssh, file://, or a path)
+//	  -method-repo  string  Git URL of BMAD-METHOD (https, ssh, file://, or a path)
+//	  -bundles-ref  string  Tag, branch or commit of bmad-bundles to clone
+//	  -method-ref   string  Tag, branch or commit of BMAD-METHOD to clone
+//	  -cache-dir    string  Source cache directory (default $XDG_CACHE_HOME/bmad2vibe)
+//	  -no-cache             Clone into a temp dir instead of using the cache
+//	  -config       string  Project config file (default ./bmad2vibe.toml if present)
+//	  -on-conflict  string  Strategy for hand-edited outputs: skip, new, merge, force
+//	  -inline-threshold int Split skills above this many bytes into reference files (default 65536, 0 never)
+//	  -ignore       string  Diagnostics to suppress: BV010, orphan-prompt, BV011:prompts/*.md
+//	  -werror               Report warnings as errors
+//	  -report-format string text or json (json goes to stdout unless -report-file is set)
+//	  -report-file  string  Write the report to this file
+//	  -tokenizer    string  Token estimate for size budgets: chars, words, mixed (default chars)
+//	  -var          name=value  Set a BMAD variable such as user_name (repeatable)
+//	  -output-folder string  Folder for BMAD artifacts, relative to the project (default _bmad-output)
+//	  -planning-artifacts string  Folder for planning artifacts (default <output-folder>/planning-artifacts)
+//	  -implementation-artifacts string  Folder for implementation artifacts (default <output-folder>/implementation-artifacts)
+//
+//	bmad2vibe cache list|prune [flags]
+//	  -cache-dir    string  Source cache directory
+//	  -keep         int     prune: checkouts kept per repository (default 1)
+//	  -all                  prune: remove the whole cache
+//	  -dry-run              prune: show what would be removed
+//
+//	bmad2vibe uninstall [flags]
+//	  -vibe-home    string  Vibe home directory (default ~/.vibe, or ./.vibe with -scope project)
+//	  -claude-home  string  Claude Code directory (default ~/.claude, or ./.claude with -scope project)
+//	  -target       string  vibe or claude (default vibe)
+//	  -scope        string  global or project (default global)
+//	  -modules      string  Comma-separated modules to remove (default: all)
+//	  -dry-run              Show what would be removed
+//	  -verbose              Verbose output
 package main
 
 import (
@@ -25,18 +65,12 @@ import (
 	"io"
 	"log"
 	"os"
-	"os/exec"
+	"path"
 	"path/filepath"
-	"regexp"
 	"sort"
 	"strings"
 )
 
-const (
-	bmadBundlesRepo = "https://github.com/bmad-code-org/bmad-bundles.git"
-	bmadMethodRepo  = "https://github.com/bmad-code-org/BMAD-METHOD.git"
-)
-
 // --- Safety and tools mapping ---
 
 var agentSafetyMap = map[string]string{
@@ -62,6 +96,7 @@ var safetyToolsMap = map[string][]string{
 	"safe":        {"read_file", "grep", "list_dir", "ask_user_question"},
 	"neutral":     {"read_file", "grep", "list_dir", "write_file", "search_replace", "ask_user_question"},
 	"destructive": {"read_file", "grep", "list_dir", "write_file", "search_replace", "bash", "ask_user_question", "task"},
+	"yolo":        {"read_file", "grep", "list_dir", "write_file", "search_replace", "bash", "ask_user_question", "task"},
 }
 
 // --- Types ---
@@ -73,18 +108,50 @@ type config struct {
 	verbose  bool
 	cleanup  bool
 	tmpDir   string
+	cacheDir string    // source cache, empty to clone into tmpDir
+	out      io.Writer // progress messages; stdout unless it carries the JSON report
+
+	target      target // tool installed for; vibeHome is its install directory
+	scope       string // scopeGlobal or scopeProject
+	projectRoot string // project installs only
+	globalIndex bool   // -global-index: write indexes that every project loads
+	paths       vibePaths
+
+	bundles sourceRepo
+	method  sourceRepo
+
+	file       *fileConfig // bmad2vibe.toml settings
+	onConflict string      // -on-conflict, overrides the config default
+
+	inlineThreshold int    // skills above this size are split; 0 never splits
+	tokenizer       string // token estimate used for size budgets
+
+	varOverrides varFlags            // -var, overrides every other source
+	outputFlags  outputConfig        // -output-folder & co., override -var
+	vars         map[string]bmadVars // per module, filled by moduleVars
+
+	prevManifest *manifest // outputs of the previous run
+	manifest     *manifest // outputs of this run, filled by writeFile
 }
 
-type conversionReport struct {
-	agents   []string
-	prompts  []string
-	skills   []string
-	warnings []string
-	errors   []string
+// progress returns the writer for progress messages, stdout by default.
+func (cfg *config) progress() io.Writer {
+	if cfg.out == nil {
+		return os.Stdout
+	}
+	return cfg.out
 }
 
-func (r *conversionReport) warn(msg string) { r.warnings = append(r.warnings, msg) }
-func (r *conversionReport) err(msg string)  { r.errors = append(r.errors, msg) }
+func (cfg *config) printf(format string, a ...any) { fmt.Fprintf(cfg.progress(), format, a...) }
+func (cfg *config) println(a ...any)               { fmt.Fprintln(cfg.progress(), a...) }
+
+// gitOutput is where git's own output goes: shown only in verbose mode.
+func (cfg *config) gitOutput() io.Writer {
+	if cfg.verbose {
+		return cfg.progress()
+	}
+	return nil
+}
 
 type agentMeta struct {
 	Slug        string
@@ -94,26 +161,92 @@ type agentMeta struct {
 	Description string
 }
 
+// defaultInlineThreshold is the SKILL.md size above which workflow skills
+// are split into reference files.
+const defaultInlineThreshold = 64 * 1024
+
 // --- Main ---
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "uninstall":
+			runUninstall(os.Args[2:])
+			return
+		case "cache":
+			runCache(os.Args[2:])
+			return
+		}
+	}
+
 	var (
-		vibeHome   = flag.String("vibe-home", "", "Vibe home directory (default ~/.vibe)")
+		vibeHome   = flag.String("vibe-home", "", "Vibe home directory (default ~/.vibe, or ./.vibe with -scope project)")
+		claudeHome = flag.String("claude-home", "", "Claude Code directory for -target claude (default ~/.claude, or ./.claude with -scope project)")
+		targetList = flag.String("target", "vibe", "Comma-separated tools to install for: vibe, claude")
+		scope      = flag.String("scope", scopeGlobal, "Install scope: global (~/.vibe) or project (./.vibe and ./AGENTS.md)")
+		globalIdx  = flag.Bool("global-index", false, "With -scope global and -target claude, add the BMAD section to ~/.claude/CLAUDE.md, which every project loads")
 		modules    = flag.String("modules", "", "Comma-separated modules to convert (auto-discovered if empty)")
 		dryRun     = flag.Bool("dry-run", false, "Show what would be done without writing files")
 		verbose    = flag.Bool("verbose", false, "Verbose output")
 		cleanup    = flag.Bool("cleanup", true, "Remove temp cloned repos after conversion")
+		cacheDir   = flag.String("cache-dir", defaultCacheDir(), "Source cache directory")
+		noCache    = flag.Bool("no-cache", false, "Clone sources into a temp dir instead of using the cache")
 		bundlesDir = flag.String("bundles-dir", "", "Use local bmad-bundles dir instead of cloning")
 		methodDir  = flag.String("method-dir", "", "Use local BMAD-METHOD dir instead of cloning")
+		bundlesURL = flag.String("bundles-repo", "", "Git URL of bmad-bundles, e.g. a fork (default "+bmadBundlesRepo+")")
+		methodURL  = flag.String("method-repo", "", "Git URL of BMAD-METHOD, e.g. a fork (default "+bmadMethodRepo+")")
+		bundlesRef = flag.String("bundles-ref", "", "Tag, branch or commit of bmad-bundles to clone (default: default branch)")
+		methodRef  = flag.String("method-ref", "", "Tag, branch or commit of BMAD-METHOD to clone (default: default branch)")
+		configPath = flag.String("config", "", "Project config file (default ./"+configFileName+" if present)")
+		onConflict = flag.String("on-conflict", "", "Strategy for hand-edited outputs: skip, new, merge, force (default skip)")
+		tokenizer  = flag.String("tokenizer", "", "Token estimate for size budgets: "+tokenizerNames()+" (default "+defaultTokenizer+")")
+		ignore     = flag.String("ignore", "", "Comma-separated diagnostics to suppress: codes or names, optionally CODE:glob")
+		werror     = flag.Bool("werror", false, "Report warnings as errors")
+		reportFmt  = flag.String("report-format", "text", "Report format: text or json")
+		reportFile = flag.String("report-file", "", "Write the report to this file (default: stdout)")
+		outFolder  = flag.String("output-folder", "", "Folder for BMAD artifacts, relative to the project root (default _bmad-output)")
+		planDir    = flag.String("planning-artifacts", "", "Folder for planning artifacts (default <output-folder>/planning-artifacts)")
+		implDir    = flag.String("implementation-artifacts", "", "Folder for implementation artifacts (default <output-folder>/implementation-artifacts)")
+		inlineMax  = flag.Int("inline-threshold", -1, fmt.Sprintf("Split skills larger than this many bytes into SKILL.md + reference files; 0 never splits (default %d)", defaultInlineThreshold))
 	)
+	vars := varFlags{}
+	flag.Var(vars, "var", "Set a BMAD variable, e.g. -var user_name=Ada (repeatable)")
 	flag.Parse()
 
-	if *vibeHome == "" {
-		home, err := os.UserHomeDir()
+	if *reportFmt != "text" && *reportFmt != "json" {
+		log.Fatalf("invalid -report-format %q (want text or json)", *reportFmt)
+	}
+	// A JSON report on stdout must be the only thing there: progress goes to stderr.
+	var progress io.Writer = os.Stdout
+	if *reportFmt == "json" && *reportFile == "" {
+		progress = os.Stderr
+	}
+	if *tokenizer != "" && tokenizers[*tokenizer] == nil {
+		log.Fatalf("invalid -tokenizer %q (want %s)", *tokenizer, tokenizerNames())
+	}
+	if *inlineMax < -1 {
+		log.Fatalf("invalid -inline-threshold %d", *inlineMax)
+	}
+	if *onConflict != "" && !validConflictStrategy(*onConflict) {
+		log.Fatalf("invalid -on-conflict %q (want skip, new, merge or force)", *onConflict)
+	}
+	fileCfg, configFiles, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("cannot load config: %v", err)
+	}
+
+	tgts, err := parseTargets(*targetList)
+	if err != nil {
+		log.Fatalf("invalid -target: %v", err)
+	}
+	homes := map[string]string{"vibe": *vibeHome, "claude": *claudeHome}
+	installDirs := make(map[string]string)
+	var projectRoot string
+	for _, t := range tgts {
+		installDirs[t.name()], projectRoot, err = resolveScope(*scope, homes[t.name()], t.dir())
 		if err != nil {
-			log.Fatalf("cannot determine home directory: %v", err)
+			log.Fatal(err)
 		}
-		*vibeHome = filepath.Join(home, ".vibe")
 	}
 
 	tmpDir, err := os.MkdirTemp("", "bmad2vibe-*")
@@ -123,27 +256,75 @@ func main() {
 	if *cleanup {
 		defer os.RemoveAll(tmpDir)
 	} else {
-		fmt.Printf("📁 Temp directory: %s\n", tmpDir)
+		fmt.Fprintf(progress, "📁 Temp directory: %s\n", tmpDir)
 	}
 
 	cfg := &config{
-		vibeHome: *vibeHome,
-		dryRun:   *dryRun,
-		verbose:  *verbose,
-		cleanup:  *cleanup,
-		tmpDir:   tmpDir,
+		out:         progress,
+		scope:       *scope,
+		projectRoot: projectRoot,
+		globalIndex: *globalIdx,
+		dryRun:      *dryRun,
+		verbose:     *verbose,
+		cleanup:     *cleanup,
+		tmpDir:      tmpDir,
+		cacheDir:    *cacheDir,
+
+		file:            fileCfg,
+		onConflict:      *onConflict,
+		inlineThreshold: defaultInlineThreshold,
+		tokenizer:       firstNonEmpty(*tokenizer, fileCfg.Budgets.Tokenizer, defaultTokenizer),
+		varOverrides:    vars,
+		outputFlags:     outputConfig{Folder: *outFolder, PlanningArtifacts: *planDir, ImplementationArtifacts: *implDir},
+
+		bundles: sourceRepo{Name: "bmad-bundles", Flag: "bundles", URL: firstNonEmpty(*bundlesURL, fileCfg.Sources.BundlesRepo, bmadBundlesRepo), Ref: fileCfg.Sources.BundlesRef},
+		method:  sourceRepo{Name: "BMAD-METHOD", Flag: "method", URL: firstNonEmpty(*methodURL, fileCfg.Sources.MethodRepo, bmadMethodRepo), Ref: fileCfg.Sources.MethodRef},
+	}
+	if *noCache {
+		cfg.cacheDir = ""
+	}
+	if fileCfg.Skills.InlineThreshold != nil {
+		cfg.inlineThreshold = *fileCfg.Skills.InlineThreshold
+	}
+	if *inlineMax >= 0 {
+		cfg.inlineThreshold = *inlineMax
+	}
+	if *bundlesRef != "" {
+		cfg.bundles.Ref = *bundlesRef
+	}
+	if *methodRef != "" {
+		cfg.method.Ref = *methodRef
 	}
 
-	report := &conversionReport{}
+	policy := diagPolicy{ignore: fileCfg.Diags.Ignore, werror: *werror}
+	if w := fileCfg.Diags.Werror; w != nil && *w {
+		policy.werror = true
+	}
+	for _, s := range splitTrim(*ignore, ",") {
+		r, err := parseIgnoreRule(s)
+		if err != nil {
+			log.Fatalf("invalid -ignore: %v", err)
+		}
+		policy.ignore = append(policy.ignore, r)
+	}
+	newReport := func() *conversionReport { return &conversionReport{policy: policy} }
 
-	fmt.Println("🚀 bmad2vibe — BMAD Method → Mistral Vibe converter")
-	fmt.Printf("   Target: %s\n", cfg.vibeHome)
+	cfg.println("🚀 bmad2vibe — BMAD Method → Mistral Vibe converter")
+	for _, t := range tgts {
+		cfg.printf("   Target: %s (%s, %s)\n", installDirs[t.name()], t.product(), cfg.scope)
+	}
 	if cfg.dryRun {
-		fmt.Println("   ⚠️  DRY RUN — no files will be written")
+		cfg.println("   ⚠️  DRY RUN — no files will be written")
+	}
+	for _, f := range configFiles {
+		cfg.printf("   Config: %s\n", f)
 	}
 
 	// Step 1: Get sources
-	bDir, mDir := resolveSources(cfg, *bundlesDir, *methodDir)
+	if err := resolveSources(cfg, *bundlesDir, *methodDir); err != nil {
+		log.Fatal(err)
+	}
+	bDir, mDir := cfg.bundles.Dir, cfg.method.Dir
 
 	// Step 2: Resolve modules
 	if *modules != "" {
@@ -151,90 +332,98 @@ func main() {
 	} else {
 		cfg.modules = discoverModules(bDir, mDir)
 	}
-	fmt.Printf("   Modules: %v\n", cfg.modules)
-	fmt.Println()
+	cfg.printf("   Modules: %v\n", cfg.modules)
+	cfg.println()
+
+	// Phase 1 needs the skills of every module, whatever the target.
+	skills := buildSkillIndex(mDir, cfg.modules)
+
+	var runs []targetRun
+	for _, t := range tgts {
+		tc := *cfg
+		tc.target, tc.vibeHome = t, installDirs[t.name()]
+		tc.paths = newVibePaths(tc.vibeHome, tc.scope, tc.projectRoot)
+		tc.paths.skills = skills
+		report := newReport()
+		prev, err := loadManifest(tc.vibeHome)
+		if err != nil {
+			report.warn(diagInvalidManifest, manifestName, "ignoring previous manifest: %v", err)
+		}
+		tc.prevManifest, tc.manifest = prev, newManifest()
+
+		if len(tgts) > 1 {
+			cfg.printf("%s\n🎯 %s → %s\n%s\n\n", strings.Repeat("─", 60), t.product(), tc.vibeHome, strings.Repeat("─", 60))
+		}
+		convert(&tc, bDir, mDir, skills, report)
+		runs = append(runs, targetRun{cfg: &tc, report: report})
+	}
 
+	failed := false
+	for _, r := range runs {
+		printReport(progress, r.cfg, r.report)
+		failed = failed || len(r.report.errors) > 0
+	}
+	if *reportFile != "" || *reportFmt == "json" {
+		if err := writeReportFile(os.Stdout, *reportFile, *reportFmt, runs); err != nil {
+			log.Fatalf("cannot write report: %v", err)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// convert runs the conversion phases for the target of cfg.
+func convert(cfg *config, bDir, mDir string, skills skillIndex, report *conversionReport) {
 	// Step 3: Create target dirs
-	ensureDirs(cfg, "agents", "prompts", "skills")
+	ensureDirs(cfg, "agents", path.Dir(cfg.target.promptPath("")), "skills")
 
-	// Phase 1: Agents (bundles XML → TOML + prompt)
-	fmt.Println("📋 Phase 1: Converting agents...")
+	// Phase 1: Agents (bundles XML or agent.yaml → agent + prompt)
+	cfg.println("📋 Phase 1: Converting agents...")
 	for _, mod := range cfg.modules {
-		convertAgents(cfg, mod, bDir, report)
+		convertAgents(cfg, mod, bDir, mDir, skills, report)
 	}
 
 	// Phase 2: Workflows → skills
-	fmt.Println("\n⚙️  Phase 2: Converting workflows → skills...")
+	cfg.println("\n⚙️  Phase 2: Converting workflows → skills...")
 	for _, mod := range cfg.modules {
 		convertWorkflows(cfg, mod, mDir, report)
 	}
 
 	// Phase 3: Tasks/tools → skills
-	fmt.Println("\n🔧 Phase 3: Converting tasks/tools → skills...")
+	cfg.println("\n🔧 Phase 3: Converting tasks/tools → skills...")
 	for _, mod := range cfg.modules {
 		convertTasks(cfg, mod, mDir, report)
 	}
 
 	// Phase 4: Workflow shortcut agents
-	fmt.Println("\n🎯 Phase 4: Generating workflow shortcut agents...")
+	cfg.println("\n🎯 Phase 4: Generating workflow shortcut agents...")
 	for _, mod := range cfg.modules {
 		generateWorkflowAgents(cfg, mod, mDir, report)
 	}
 
 	// Phase 5: Copy supporting data
-	fmt.Println("\n📄 Phase 5: Copying supporting data...")
+	cfg.println("\n📄 Phase 5: Copying supporting data...")
 	for _, mod := range cfg.modules {
 		copyModuleData(cfg, mod, mDir, report)
 	}
 
+	// Outputs whose source disappeared
+	removeStale(cfg, report)
+
 	// Phase 6: AGENTS.md
-	fmt.Println("\n📝 Phase 6: Generating AGENTS.md...")
+	cfg.printf("\n📝 Phase 6: Generating %s...\n", cfg.target.index())
 	generateAgentsMD(cfg, report)
 
-	// Phase 7: Validate
-	fmt.Println("\n🔍 Phase 7: Validating...")
-	validate(cfg, report)
-
-	printReport(cfg, report)
-}
-
-// --- Source resolution ---
-
-func resolveSources(cfg *config, bundlesFlag, methodFlag string) (string, string) {
-	bDir := bundlesFlag
-	mDir := methodFlag
-
-	if bDir == "" {
-		bDir = filepath.Join(cfg.tmpDir, "bmad-bundles")
-		if err := cloneRepo(bmadBundlesRepo, bDir, cfg.verbose); err != nil {
-			log.Fatalf("failed to clone bmad-bundles: %v", err)
-		}
-	} else {
-		fmt.Printf("   📂 Using local bundles: %s\n", bDir)
-	}
-
-	if mDir == "" {
-		mDir = filepath.Join(cfg.tmpDir, "BMAD-METHOD")
-		if err := cloneRepo(bmadMethodRepo, mDir, cfg.verbose); err != nil {
-			log.Fatalf("failed to clone BMAD-METHOD: %v", err)
+	if !cfg.dryRun {
+		if err := cfg.manifest.save(cfg.vibeHome); err != nil {
+			report.err(diagIO, manifestName, "write: %v", err)
 		}
-	} else {
-		fmt.Printf("   📂 Using local method: %s\n", mDir)
 	}
-	return bDir, mDir
-}
 
-func cloneRepo(url, dest string, verbose bool) error {
-	fmt.Printf("   📥 Cloning %s...\n", url)
-	cmd := exec.Command("git", "clone", "--depth", "1", url, dest)
-	if verbose {
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-	} else {
-		cmd.Stdout = io.Discard
-		cmd.Stderr = io.Discard
-	}
-	return cmd.Run()
+	// Phase 7: Validate
+	cfg.println("\n🔍 Phase 7: Validating...")
+	validate(cfg, report)
 }
 
 // discoverModules scans both source repos and returns the union of module names
@@ -253,7 +442,7 @@ func discoverModules(bundlesDir, methodDir string) []string {
 		}
 	}
 
-	// Modules with workflows or tasks in BMAD-METHOD/src/
+	// Modules with agents, workflows or tasks in BMAD-METHOD/src/
 	srcDir := filepath.Join(methodDir, "src")
 	if entries, err := os.ReadDir(srcDir); err == nil {
 		for _, e := range entries {
@@ -261,7 +450,7 @@ func discoverModules(bundlesDir, methodDir string) []string {
 				continue
 			}
 			mod := e.Name()
-			if dirExists(filepath.Join(srcDir, mod, "workflows")) || dirExists(filepath.Join(srcDir, mod, "tasks")) {
+			if dirExists(filepath.Join(srcDir, mod, "agents")) || dirExists(filepath.Join(srcDir, mod, "workflows")) || dirExists(filepath.Join(srcDir, mod, "tasks")) {
 				seen[mod] = true
 			}
 		}
@@ -284,55 +473,83 @@ func ensureDirs(cfg *config, subdirs ...string) {
 	}
 }
 
-// --- Phase 1: Agent conversion (XML bundles → TOML + prompt) ---
+// --- Phase 1: Agent conversion (XML bundles or agent.yaml → TOML + prompt) ---
 
-func convertAgents(cfg *config, module, bundlesDir string, report *conversionReport) {
-	agentsDir := filepath.Join(bundlesDir, module, "agents")
-	entries, err := os.ReadDir(agentsDir)
-	if err != nil {
+func convertAgents(cfg *config, module, bundlesDir, methodDir string, skills skillIndex, report *conversionReport) {
+	sources := agentSources(module, bundlesDir, methodDir)
+	if len(sources) == 0 {
 		if cfg.verbose {
-			fmt.Printf("   (no agents dir for module %q in bundles — skipping)\n", module)
+			cfg.printf("   (no agents for module %q — skipping)\n", module)
 		}
 		return
 	}
 
-	for _, e := range entries {
-		if e.IsDir() || !strings.HasSuffix(e.Name(), ".xml") {
-			continue
-		}
-		slug := strings.TrimSuffix(e.Name(), ".xml")
-		xmlPath := filepath.Join(agentsDir, e.Name())
+	for _, as := range sources {
+		slug := as.Slug
+		name := filepath.Base(as.Path)
 
-		raw, err := os.ReadFile(xmlPath)
+		raw, err := os.ReadFile(as.Path)
 		if err != nil {
-			report.err(fmt.Sprintf("agent %s/%s: read: %v", module, slug, err))
+			report.err(diagIO, cfg.sourceFile(as.Path), "agent %s/%s: read: %v", module, slug, err)
 			continue
 		}
+		origin := cfg.bundles.label()
+		if as.Compiled {
+			compiled, err := compileAgentYAML(raw, module, slug)
+			if err != nil {
+				report.err(diagAgentCompile, cfg.sourceFile(as.Path), "agent %s/%s: compile: %v", module, slug, err)
+				continue
+			}
+			raw = []byte(compiled)
+			origin = cfg.method.label()
+		}
 		rawStr := string(raw)
 
-		meta := extractAgentMeta(slug, rawStr)
+		bundle, err := parseAgentBundle(raw)
+		if err != nil {
+			report.err(diagAgentParse, cfg.sourceFile(as.Path), "agent %s/%s: parse: %v", module, slug, err)
+			continue
+		}
+		meta := bundle.meta(slug)
 		vibeSlug := fmt.Sprintf("bmad-%s-%s", module, slug)
-		safety := safetyForAgent(slug)
 
-		toml := buildAgentTOML(vibeSlug, module, meta, safety)
-		tomlPath := filepath.Join(cfg.vibeHome, "agents", vibeSlug+".toml")
-
-		prompt := buildAgentPrompt(module, slug, meta, rawStr)
-		promptPath := filepath.Join(cfg.vibeHome, "prompts", vibeSlug+".md")
+		menu, unresolved := resolveMenu(bundle.Agent.Menu, skills)
+		for _, u := range unresolved {
+			report.err(diagUnresolvedMenu, cfg.sourceFile(as.Path), "agent %s/%s: menu item %q references %s, which is not a converted workflow or task", module, slug, u.Trigger, u.Ref)
+		}
+		pol := agentPolicy(cfg, module, slug)
+
+		agent := buildAgent(vibeSlug, module, meta, pol, origin)
+		vars := cfg.moduleVars(module)
+		promptPath := filepath.Join(cfg.vibeHome, cfg.target.promptPath(vibeSlug))
+		var reported []string
+		for _, u := range unresolved {
+			reported = append(reported, u.Ref)
+		}
+		agent.Prompt = buildAgentPrompt(module, slug, meta, menu, vars.expand(vars.preloadConfig(rawStr)), origin, cfg.paths, cfg.outputs(module), cfg.target)
+		agent.Prompt = cfg.renderRefs(promptPath, agent.Prompt, report, reported...)
+		cfg.checkPlaceholders(promptPath, module, agent.Prompt, nil, report)
 
 		if cfg.verbose {
-			fmt.Printf("   ✅ %s/%s → agent + prompt\n", module, slug)
+			from := "bundle"
+			if as.Compiled {
+				from = "compiled from " + name
+			}
+			cfg.printf("   ✅ %s/%s → agent + prompt (%s)\n", module, slug, from)
+			cfg.printf("      🛡️  %s\n", pol)
 		}
 
-		writeFile(cfg, tomlPath, toml, report)
-		writeFile(cfg, promptPath, prompt, report)
+		src := source{Module: module, Path: as.Path, Safety: pol.Safety}
+		for _, f := range cfg.target.agentFiles(agent) {
+			writeFile(cfg, filepath.Join(cfg.vibeHome, filepath.FromSlash(f.name)), f.content, src, report)
+		}
 		report.agents = append(report.agents, vibeSlug)
 		report.prompts = append(report.prompts, vibeSlug)
 	}
 }
 
-func buildAgentTOML(vibeSlug, module string, meta agentMeta, safety string) string {
-	tools := safetyToolsMap[safety]
+// buildAgent describes a persona agent; the caller adds its prompt.
+func buildAgent(vibeSlug, module string, meta agentMeta, pol policy, origin string) agentSpec {
 	displayName := fmt.Sprintf("BMAD %s %s", strings.ToUpper(module), meta.Title)
 	if meta.Name != "" && meta.Name != meta.Title {
 		displayName += fmt.Sprintf(" (%s)", meta.Name)
@@ -342,23 +559,21 @@ func buildAgentTOML(vibeSlug, module string, meta agentMeta, safety string) stri
 		desc = fmt.Sprintf("BMAD %s agent: %s", strings.ToUpper(module), meta.Title)
 	}
 
-	var b strings.Builder
-	w := func(f string, a ...any) { fmt.Fprintf(&b, f, a...) }
-
-	w("# Auto-generated by bmad2vibe\n")
-	w("# BMAD Agent: %s\n", vibeSlug)
-	w("# Source module: %s | Persona: %s %s\n\n", module, meta.Icon, meta.Name)
-	w("display_name = %q\n", displayName)
-	w("description = %q\n", desc)
-	w("safety = %q\n", safety)
-	w("auto_approve = %v\n", safety == "safe")
-	w("system_prompt_id = %q\n", vibeSlug)
-	w("\nenabled_tools = [%s]\n", joinQuoted(tools))
-
-	return b.String()
+	return agentSpec{
+		Slug: vibeSlug,
+		Comments: []string{
+			"Auto-generated by bmad2vibe",
+			"BMAD Agent: " + vibeSlug,
+			fmt.Sprintf("Source module: %s | Persona: %s %s", module, meta.Icon, meta.Name),
+			"Source: " + origin,
+		},
+		DisplayName: displayName,
+		Description: desc,
+		Policy:      pol,
+	}
 }
 
-func buildAgentPrompt(module, slug string, meta agentMeta, rawXML string) string {
+func buildAgentPrompt(module, slug string, meta agentMeta, menu []menuEntry, rawXML, origin string, paths vibePaths, out outputFolders, t target) string {
 	var b strings.Builder
 	w := func(f string, a ...any) { fmt.Fprintf(&b, f, a...) }
 
@@ -367,29 +582,48 @@ func buildAgentPrompt(module, slug string, meta agentMeta, rawXML string) string
 		w(" (%s)", meta.Name)
 	}
 	w("\n\n")
-	w("> Module: %s | Agent: %s | Generated by bmad2vibe\n\n", strings.ToUpper(module), slug)
-
-	// Vibe adaptation layer — critical for correct execution
-	w("## Vibe Runtime Adaptation\n\n")
-	w("You are running inside **Mistral Vibe** CLI, NOT Claude Code/Cursor/Windsurf.\n")
-	w("Apply these substitutions when following BMAD instructions:\n\n")
-	w("| BMAD reference | Vibe equivalent |\n")
+	w("> Module: %s | Agent: %s | Source: %s | Generated by bmad2vibe\n\n", strings.ToUpper(module), slug, origin)
+
+	// Runtime adaptation layer — critical for correct execution
+	intro, rows := t.runtime()
+	w("## %s Runtime Adaptation\n\n", t.short())
+	w("%s\n", intro)
+	w("BMAD configuration variables are already substituted. Apply these\n")
+	w("substitutions when following BMAD instructions:\n\n")
+	w("| BMAD reference | %s equivalent |\n", t.short())
 	w("|---|---|\n")
 	w("| `{project-root}` | Current working directory |\n")
-	w("| `{output_folder}` | `_bmad-output/` |\n")
-	w("| `{planning_artifacts}` | `_bmad-output/planning-artifacts/` |\n")
-	w("| `{implementation_artifacts}` | `_bmad-output/implementation-artifacts/` |\n")
+	w("| `{output_folder}` | `%s` |\n", out.Root)
+	w("| `{planning_artifacts}` | `%s` |\n", out.Planning)
+	w("| `{implementation_artifacts}` | `%s` |\n", out.Implementation)
 	w("| Slash commands (`/bmad-...`) | Execute the workflow instructions inline |\n")
-	w("| `ask_user_question` | Vibe interactive question tool |\n")
-	w("| `workflow.xml` engine | Follow workflow steps sequentially |\n")
-	w("| `task` tool (subagent) | Vibe `task` tool for delegation |\n\n")
+	for _, r := range rows {
+		w("%s\n", r)
+	}
+	w("\n")
 
-	w("When a menu item references a workflow, read its SKILL.md from\n")
-	w("`~/.vibe/skills/bmad-%s-<workflow-name>/SKILL.md` and execute it.\n\n", module)
+	if len(menu) > 0 {
+		w("## Menu Commands\n\n")
+		w("When the user picks a menu item that maps to a skill, read that SKILL.md\n")
+		w("and execute it instead of resolving the BMAD path from the definition below.\n\n")
+		w("| Trigger | Description | Skill |\n")
+		w("|---|---|---|\n")
+		for _, m := range menu {
+			target := "(inline action)"
+			switch {
+			case m.Skill != "":
+				target = fmt.Sprintf("`%s/SKILL.md`", paths.skill(m.Skill))
+			case m.Ref != "":
+				target = "(unavailable)"
+			}
+			w("| `%s` | %s | %s |\n", m.Trigger, tableCell(m.Description), target)
+		}
+		w("\n")
+	}
 
 	// Full BMAD agent — LLMs handle XML natively
 	w("## Full Agent Definition\n\n")
-	w("Follow the agent specification below exactly, adapting tool calls to Vibe.\n\n")
+	w("Follow the agent specification below exactly, adapting tool calls to %s.\n\n", t.short())
 	w("```xml\n%s\n```\n", strings.TrimSpace(rawXML))
 
 	return b.String()
@@ -401,7 +635,7 @@ func convertWorkflows(cfg *config, module, methodDir string, report *conversionR
 	workflowsDir := filepath.Join(methodDir, "src", module, "workflows")
 	if !dirExists(workflowsDir) {
 		if cfg.verbose {
-			fmt.Printf("   (no workflows dir for module %q — skipping)\n", module)
+			cfg.printf("   (no workflows dir for module %q — skipping)\n", module)
 		}
 		return
 	}
@@ -411,77 +645,174 @@ func convertWorkflows(cfg *config, module, methodDir string, report *conversionR
 			return nil
 		}
 		name := info.Name()
-		ext := filepath.Ext(name)
-		if !strings.HasPrefix(name, "workflow") || (ext != ".md" && ext != ".yaml") {
+		if !isWorkflowFile(name) {
 			return nil
 		}
 
 		rel, _ := filepath.Rel(workflowsDir, path)
 		skillSlug := buildSkillSlug(module, rel, name)
 
-		content, err := os.ReadFile(path)
-		if err != nil {
-			report.warn(fmt.Sprintf("read workflow %s: %v", rel, err))
-			return nil
-		}
-
-		steps := collectStepDirs(filepath.Dir(path))
-		data := collectFiles(filepath.Join(filepath.Dir(path), "data"), "")
-		templates := collectNamedFiles(filepath.Dir(path), "template", "tmpl")
-		templatesDir := collectFiles(filepath.Join(filepath.Dir(path), "templates"), "")
-		templates = append(templates, templatesDir...)
-
-		skill := buildWorkflowSkill(module, skillSlug, string(content), steps, data, templates)
+		wfDir := filepath.Dir(path)
 		skillDir := filepath.Join(cfg.vibeHome, "skills", skillSlug)
 		skillPath := filepath.Join(skillDir, "SKILL.md")
+		installed := cfg.paths.skill(skillSlug)
+
+		var body, description string
+		var order []string
+		var data, templates []namedContent
+		var yamlWF *yamlWorkflow
+		vars, declared := cfg.moduleVars(module), map[string]bool(nil)
+
+		if filepath.Ext(name) == ".yaml" {
+			// workflow.yaml declares its files: inline those rather than the YAML.
+			yamlWF, err = loadYAMLWorkflow(path, methodDir, rel, vars, report)
+			if err != nil {
+				report.err(diagWorkflowParse, cfg.sourceFile(path), "workflow %s: parse: %v", rel, err)
+				return nil
+			}
+			body = yamlWF.body(installed, false)
+			description = oneLine(yamlWF.def.Description)
+			order = yamlWF.def.Steps
+			data, templates = yamlWF.data, yamlWF.templates
+			vars, declared = yamlWF.vars, yamlWF.def.declared()
+		} else {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				report.warn(diagIO, cfg.sourceFile(path), "read workflow %s: %v", rel, err)
+				return nil
+			}
+			body = vars.expand(string(content))
+			description = skillDescription(body)
+			order = frontmatterSteps(body)
+			// Names are relative to the workflow dir, which a split skill mirrors.
+			data = prefixNames("data/", collectFiles(filepath.Join(wfDir, "data"), ""))
+			templates = collectNamedFiles(wfDir, "template", "tmpl")
+			templates = append(templates, prefixNames("templates/", collectFiles(filepath.Join(wfDir, "templates"), ""))...)
+			data, templates = vars.expandAll(data), vars.expandAll(templates)
+		}
+
+		description = describeSkill(module, "workflow", skillSlug, description, report)
+		steps := collectStepDirs(wfDir, order)
+		for i := range steps {
+			steps[i].steps = vars.expandAll(steps[i].steps)
+		}
+		skill := buildWorkflowSkill(module, skillSlug, description, body, steps, data, templates, cfg.method.label(), cfg.outputs(module), cfg.target, nil)
+
+		var split *splitSkill
+		if cfg.inlineThreshold > 0 && len(skill) > cfg.inlineThreshold {
+			split = &splitSkill{dir: installed}
+			if yamlWF != nil {
+				split.have = yamlWF.files
+				body = yamlWF.body(installed, true)
+			} else {
+				// The referenced files now exist under the skill dir.
+				body = strings.ReplaceAll(body, "{installed_path}", installed)
+			}
+			skill = buildWorkflowSkill(module, skillSlug, description, body, steps, data, templates, cfg.method.label(), cfg.outputs(module), cfg.target, split)
+		}
 
 		if cfg.verbose {
-			fmt.Printf("   ⚙️  %s → %s\n", rel, skillSlug)
+			cfg.printf("   ⚙️  %s → %s\n", rel, skillSlug)
+			if split != nil {
+				cfg.printf("      ✂️  split into SKILL.md + %d reference files\n", len(split.files))
+			}
 		}
 
 		if !cfg.dryRun {
 			os.MkdirAll(skillDir, 0o755)
 		}
-		writeFile(cfg, skillPath, skill, report)
+		skill = cfg.renderRefs(skillPath, skill, report)
+		cfg.checkPlaceholders(skillPath, module, skill, declared, report)
+		writeFile(cfg, skillPath, skill, source{Module: module, Path: path}, report)
+		if yamlWF != nil {
+			// Referenced files also live next to SKILL.md, where
+			// {installed_path} now points.
+			for _, f := range sortedKeys(yamlWF.files) {
+				content := yamlWF.def.resolve(yamlWF.files[f], installed)
+				out := filepath.Join(skillDir, filepath.FromSlash(f))
+				content = cfg.renderRefs(out, content, report)
+				cfg.checkPlaceholders(out, module, content, declared, report)
+				writeFile(cfg, out, content, source{Module: module, Path: filepath.Join(wfDir, filepath.FromSlash(f))}, report)
+			}
+		}
+		if split != nil {
+			for _, f := range split.files {
+				content := strings.ReplaceAll(f.content, "{installed_path}", installed)
+				out := filepath.Join(skillDir, filepath.FromSlash(f.name))
+				content = cfg.renderRefs(out, content, report)
+				cfg.checkPlaceholders(out, module, content, declared, report)
+				writeFile(cfg, out, content, source{Module: module, Path: filepath.Join(wfDir, filepath.FromSlash(f.name))}, report)
+			}
+		}
 		report.skills = append(report.skills, skillSlug)
 		return nil
 	})
 }
 
-func buildWorkflowSkill(module, slug, content string, steps, data, templates []namedContent) string {
+// buildWorkflowSkill renders a workflow skill. With split == nil, steps,
+// templates and data are inlined; otherwise SKILL.md only links to them and
+// they are collected in split.files.
+func buildWorkflowSkill(module, slug, description, content string, steps []stepGroup, data, templates []namedContent, origin string, out outputFolders, t target, split *splitSkill) string {
 	var b strings.Builder
 	w := func(f string, a ...any) { fmt.Fprintf(&b, f, a...) }
 
 	// AgentSkills spec frontmatter
 	w("---\n")
 	w("name: %s\n", slug)
-	w("description: \"BMAD %s workflow — auto-generated by bmad2vibe\"\n", strings.ToUpper(module))
+	w("description: %s\n", yamlQuote(description))
 	w("license: MIT\n")
 	w("user-invocable: true\n")
 	w("allowed-tools:\n")
-	for _, t := range []string{"read_file", "write_file", "search_replace", "grep", "bash", "ask_user_question", "list_dir"} {
-		w("  - %s\n", t)
+	for _, tool := range mapTools(t, []string{"read_file", "write_file", "search_replace", "grep", "bash", "ask_user_question", "list_dir"}) {
+		w("  - %s\n", tool)
 	}
 	w("---\n\n")
 
-	w("> Auto-generated by bmad2vibe from BMAD %s module.\n", strings.ToUpper(module))
-	w("> `{project-root}` → cwd | `{output_folder}` → `_bmad-output/`\n")
-	w("> `{planning_artifacts}` → `_bmad-output/planning-artifacts/`\n")
-	w("> When instructions say \"load workflow engine\", follow steps sequentially.\n\n")
+	w("> Auto-generated by bmad2vibe from BMAD %s module (%s).\n", strings.ToUpper(module), origin)
+	w("> `{project-root}` → cwd | `{output_folder}` → `%s`\n", out.Root)
+	w("> `{planning_artifacts}` → `%s` | `{implementation_artifacts}` → `%s`\n", out.Planning, out.Implementation)
+	w("> When instructions say \"load workflow engine\", follow steps sequentially.\n")
+	if split != nil {
+		w("> Steps, templates and data are in separate files: read each one with `%s` only when you need it.\n", t.tool("read_file"))
+	}
+	w("\n")
 
 	w("%s\n", content)
 
-	if len(steps) > 0 {
+	step := func(level string, dir string, s namedContent) {
+		if split != nil {
+			w("%s. `%s`\n", level, split.ref(namedContent{name: dir + "/" + s.name, content: s.content}))
+			return
+		}
+		w("%s %s\n\n%s\n\n", level, s.name, s.content)
+	}
+	if len(steps) == 1 && steps[0].mode() == "" {
 		w("\n---\n\n# Workflow Steps\n\n")
 		w("Execute these steps in order.\n\n")
-		for _, s := range steps {
-			w("## %s\n\n%s\n\n", s.name, s.content)
+		for i, s := range steps[0].steps {
+			step(pick(split != nil, fmt.Sprint(i+1), "##"), steps[0].dir, s)
+		}
+	} else if len(steps) > 0 {
+		w("\n---\n\n# Workflow Steps\n\n")
+		w("This workflow has several modes. Run only the steps of the mode the user asked for, in order.\n\n")
+		for _, g := range steps {
+			w("## Mode: %s (`%s/`)\n\n", firstNonEmpty(g.mode(), "Default"), g.dir)
+			for i, s := range g.steps {
+				step(pick(split != nil, fmt.Sprint(i+1), "###"), g.dir, s)
+			}
+			if split != nil {
+				w("\n")
+			}
 		}
 	}
 
 	if len(templates) > 0 {
 		w("\n---\n\n# Templates\n\n")
 		for _, t := range templates {
+			if split != nil {
+				w("- `%s`\n", split.ref(t))
+				continue
+			}
 			lang := strings.TrimPrefix(filepath.Ext(t.name), ".")
 			if lang == "md" {
 				lang = "markdown"
@@ -493,6 +824,10 @@ func buildWorkflowSkill(module, slug, content string, steps, data, templates []n
 	if len(data) > 0 {
 		w("\n---\n\n# Data Files\n\n")
 		for _, d := range data {
+			if split != nil {
+				w("- `%s`\n", split.ref(d))
+				continue
+			}
 			lang := strings.TrimPrefix(filepath.Ext(d.name), ".")
 			w("## Data: %s\n\n```%s\n%s\n```\n\n", d.name, lang, d.content)
 		}
@@ -501,6 +836,30 @@ func buildWorkflowSkill(module, slug, content string, steps, data, templates []n
 	return b.String()
 }
 
+// splitSkill lays a workflow skill out as a compact SKILL.md plus reference
+// files in its directory (progressive disclosure).
+type splitSkill struct {
+	dir   string            // skill directory as referenced in prompts
+	have  map[string]string // files already written next to SKILL.md, by relative path
+	files []namedContent    // reference files to write, by relative path
+}
+
+// ref returns the path SKILL.md uses for f, whose name is relative to the
+// workflow dir, queuing f unless it is already part of the skill directory.
+func (s *splitSkill) ref(f namedContent) string {
+	if _, ok := s.have[f.name]; !ok {
+		s.files = append(s.files, f)
+	}
+	return s.dir + "/" + f.name
+}
+
+func pick(cond bool, a, b string) string {
+	if cond {
+		return a
+	}
+	return b
+}
+
 // --- Phase 3: Task/tool → skill ---
 
 func convertTasks(cfg *config, module, methodDir string, report *conversionReport) {
@@ -511,12 +870,11 @@ func convertTasks(cfg *config, module, methodDir string, report *conversionRepor
 
 	entries, _ := os.ReadDir(tasksDir)
 	for _, e := range entries {
-		ext := filepath.Ext(e.Name())
-		if e.IsDir() || (ext != ".md" && ext != ".xml") {
+		if e.IsDir() || !isTaskFile(e.Name()) {
 			continue
 		}
-		slug := strings.TrimSuffix(e.Name(), ext)
-		skillSlug := fmt.Sprintf("bmad-%s-task-%s", module, slug)
+		slug := strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
+		skillSlug := taskSkillSlug(module, e.Name())
 
 		content, err := os.ReadFile(filepath.Join(tasksDir, e.Name()))
 		if err != nil {
@@ -527,26 +885,31 @@ func convertTasks(cfg *config, module, methodDir string, report *conversionRepor
 		w := func(f string, a ...any) { fmt.Fprintf(&b, f, a...) }
 		w("---\n")
 		w("name: %s\n", skillSlug)
-		w("description: \"BMAD %s task — auto-generated by bmad2vibe\"\n", strings.ToUpper(module))
+		w("description: %s\n", yamlQuote(describeSkill(module, "task", skillSlug, skillDescription(string(content)), report)))
 		w("license: MIT\n")
 		w("user-invocable: true\n")
 		w("allowed-tools:\n")
-		w("  - read_file\n  - write_file\n  - grep\n  - bash\n  - ask_user_question\n  - list_dir\n")
+		for _, tool := range mapTools(cfg.target, []string{"read_file", "write_file", "grep", "bash", "ask_user_question", "list_dir"}) {
+			w("  - %s\n", tool)
+		}
 		w("---\n\n")
-		w("> BMAD %s task. `{project-root}` → cwd.\n\n", strings.ToUpper(module))
-		w("%s\n", string(content))
+		out := cfg.outputs(module)
+		w("> BMAD %s task (%s). `{project-root}` → cwd | `{output_folder}` → `%s`.\n\n", strings.ToUpper(module), cfg.method.label(), out.Root)
+		w("%s\n", cfg.moduleVars(module).expand(string(content)))
 
 		skillDir := filepath.Join(cfg.vibeHome, "skills", skillSlug)
 		skillPath := filepath.Join(skillDir, "SKILL.md")
+		skill := cfg.renderRefs(skillPath, b.String(), report)
+		cfg.checkPlaceholders(skillPath, module, skill, nil, report)
 
 		if cfg.verbose {
-			fmt.Printf("   🔧 %s/%s → %s\n", module, slug, skillSlug)
+			cfg.printf("   🔧 %s/%s → %s\n", module, slug, skillSlug)
 		}
 
 		if !cfg.dryRun {
 			os.MkdirAll(skillDir, 0o755)
 		}
-		writeFile(cfg, skillPath, b.String(), report)
+		writeFile(cfg, skillPath, skill, source{Module: module, Path: filepath.Join(tasksDir, e.Name())}, report)
 		report.skills = append(report.skills, skillSlug)
 	}
 }
@@ -565,8 +928,7 @@ func generateWorkflowAgents(cfg *config, module, methodDir string, report *conve
 			return nil
 		}
 		name := info.Name()
-		ext := filepath.Ext(name)
-		if !strings.HasPrefix(name, "workflow") || (ext != ".md" && ext != ".yaml") {
+		if !isWorkflowFile(name) {
 			return nil
 		}
 
@@ -576,47 +938,50 @@ func generateWorkflowAgents(cfg *config, module, methodDir string, report *conve
 		agentSlug := fmt.Sprintf("bmad-%s-%s", module, shortName)
 
 		// Don't overwrite persona agents from Phase 1
-		tomlPath := filepath.Join(cfg.vibeHome, "agents", agentSlug+".toml")
-		if fileExists(tomlPath) {
+		if cfg.generated(filepath.Join(cfg.vibeHome, cfg.target.agentPath(agentSlug))) {
 			return nil
 		}
 
 		title := toTitle(shortName)
-		safety := workflowSafety(shortName)
-		tools := safetyToolsMap[safety]
-
-		var toml strings.Builder
-		tw := func(f string, a ...any) { fmt.Fprintf(&toml, f, a...) }
-		tw("# Auto-generated workflow shortcut agent by bmad2vibe\n")
-		tw("# Runs workflow %s directly.\n\n", skillSlug)
-		tw("display_name = %q\n", "BMAD "+title)
-		tw("description = %q\n", fmt.Sprintf("BMAD %s workflow: %s", strings.ToUpper(module), title))
-		tw("safety = %q\n", safety)
-		tw("auto_approve = %v\n", safety != "destructive")
-		tw("system_prompt_id = %q\n", agentSlug)
-		tw("\nenabled_tools = [%s]\n", joinQuoted(tools))
+		pol := workflowPolicy(cfg, module, skillSlug, shortName)
+
+		agent := agentSpec{
+			Slug: agentSlug,
+			Comments: []string{
+				"Auto-generated " + shortcutMarker + " agent by bmad2vibe",
+				fmt.Sprintf("Runs workflow %s directly.", skillSlug),
+				"Source: " + cfg.method.label(),
+			},
+			DisplayName: "BMAD " + title,
+			Description: fmt.Sprintf("BMAD %s workflow: %s", strings.ToUpper(module), title),
+			Policy:      pol,
+		}
 
 		var prompt strings.Builder
 		pw := func(f string, a ...any) { fmt.Fprintf(&prompt, f, a...) }
 		pw("# BMAD Workflow: %s\n\n", title)
-		pw("> Workflow shortcut agent — auto-generated by bmad2vibe.\n\n")
+		pw("> Workflow shortcut agent — auto-generated by bmad2vibe from %s.\n\n", cfg.method.label())
 		pw("## Instructions\n\n")
-		pw("1. Read `~/.vibe/skills/%s/SKILL.md`\n", skillSlug)
+		pw("1. Read `%s/SKILL.md`\n", cfg.paths.skill(skillSlug))
 		pw("2. Follow all instructions sequentially\n")
+		out := cfg.outputs(module)
 		pw("3. Substitute `{project-root}` → cwd\n")
-		pw("4. Substitute `{output_folder}` → `_bmad-output/`\n")
-		pw("5. Substitute `{planning_artifacts}` → `_bmad-output/planning-artifacts/`\n")
-		pw("6. Use `ask_user_question` for interactive prompts\n\n")
+		pw("4. Substitute `{output_folder}` → `%s`\n", out.Root)
+		pw("5. Substitute `{planning_artifacts}` → `%s`\n", out.Planning)
+		pw("6. Substitute `{implementation_artifacts}` → `%s`\n", out.Implementation)
+		pw("7. Use `%s` for interactive prompts\n\n", cfg.target.tool("ask_user_question"))
 		pw("Skill slug: `%s`\n", skillSlug)
-
-		promptPath := filepath.Join(cfg.vibeHome, "prompts", agentSlug+".md")
+		agent.Prompt = prompt.String()
 
 		if cfg.verbose {
-			fmt.Printf("   🎯 %s → shortcut to %s\n", agentSlug, skillSlug)
+			cfg.printf("   🎯 %s → shortcut to %s\n", agentSlug, skillSlug)
+			cfg.printf("      🛡️  %s\n", pol)
 		}
 
-		writeFile(cfg, tomlPath, toml.String(), report)
-		writeFile(cfg, promptPath, prompt.String(), report)
+		src := source{Module: module, Path: path, Safety: pol.Safety}
+		for _, f := range cfg.target.agentFiles(agent) {
+			writeFile(cfg, filepath.Join(cfg.vibeHome, filepath.FromSlash(f.name)), f.content, src, report)
+		}
 		report.agents = append(report.agents, agentSlug+" (workflow)")
 		report.prompts = append(report.prompts, agentSlug)
 		return nil
@@ -633,15 +998,10 @@ func copyModuleData(cfg *config, module, methodDir string, report *conversionRep
 		}
 		dest := filepath.Join(cfg.vibeHome, "skills", fmt.Sprintf("bmad-%s-%s", module, sub))
 
-		if cfg.dryRun {
-			fmt.Printf("   [DRY] Would copy %s → %s\n", sub, dest)
-			continue
-		}
-
-		if err := copyDir(src, dest); err != nil {
-			report.warn(fmt.Sprintf("copy %s/%s: %v", module, sub, err))
+		if err := copyDir(cfg, module, src, dest, report); err != nil {
+			report.warn(diagIO, "", "copy %s/%s: %v", module, sub, err)
 		} else if cfg.verbose {
-			fmt.Printf("   📄 %s/%s copied\n", module, sub)
+			cfg.printf("   📄 %s/%s copied\n", module, sub)
 		}
 	}
 }
@@ -649,47 +1009,52 @@ func copyModuleData(cfg *config, module, methodDir string, report *conversionRep
 // --- Phase 6: AGENTS.md ---
 
 func generateAgentsMD(cfg *config, report *conversionReport) {
+	t := cfg.target
+	if !cfg.writesIndex() {
+		cfg.printf("   (skipped: %s is loaded in every project — pass -global-index to add the BMAD section)\n", cfg.indexPath())
+		return
+	}
 	if cfg.dryRun {
-		fmt.Println("   [DRY] Would generate AGENTS.md")
+		cfg.printf("   [DRY] Would generate %s\n", t.index())
 		return
 	}
-
-	agentsDir := filepath.Join(cfg.vibeHome, "agents")
-	entries, err := os.ReadDir(agentsDir)
-	if err != nil {
+	if !dirExists(filepath.Join(cfg.vibeHome, "agents")) {
 		return
 	}
 
 	var b strings.Builder
 	w := func(f string, a ...any) { fmt.Fprintf(&b, f, a...) }
 
-	w("# AGENTS.md — BMAD Method for Mistral Vibe\n\n")
-	w("Auto-generated by bmad2vibe. Copy to your project root for Vibe AGENTS.md support.\n\n")
-	w("## Persona Agents\n\n")
-	w("Launch: `vibe --agent <name>` or `Shift+Tab` in interactive mode.\n\n")
+	// In a shared index this is one section among others.
+	h := "##"
+	if cfg.sharedIndex() {
+		h = "###"
+		w("## BMAD Method for %s\n\n", t.product())
+		w("Auto-generated by bmad2vibe; agents and skills are in `%s/`.\n\n", cfg.paths.home)
+	} else {
+		w("# %s — BMAD Method for %s\n\n", t.index(), t.product())
+		w("Auto-generated by bmad2vibe. Copy to your project root for %s %s support.\n\n", t.short(), t.index())
+	}
+	if cfg.bundles.Name != "" {
+		w("Sources: %s, %s\n\n", cfg.bundles.label(), cfg.method.label())
+	}
+	w("%s Persona Agents\n\n", h)
+	w("%s\n\n", t.launchHelp())
 	w("| Agent | Command | Description |\n")
 	w("|---|---|---|\n")
 
 	var wfRows []string
-	for _, e := range entries {
-		if e.IsDir() || !strings.HasSuffix(e.Name(), ".toml") {
-			continue
-		}
-		slug := strings.TrimSuffix(e.Name(), ".toml")
-		data, _ := os.ReadFile(filepath.Join(agentsDir, e.Name()))
-		content := string(data)
-		dn := extractTOMLVal(content, "display_name")
-		desc := extractTOMLVal(content, "description")
-
-		if strings.Contains(content, "workflow shortcut") {
-			wfRows = append(wfRows, fmt.Sprintf("| %s | `vibe --agent %s` | %s |", dn, slug, desc))
+	for _, a := range t.listAgents(cfg.vibeHome) {
+		row := fmt.Sprintf("| %s | `%s` | %s |", tableCell(a.Name), t.launch(a.Slug), tableCell(a.Description))
+		if a.Shortcut {
+			wfRows = append(wfRows, row)
 		} else {
-			w("| %s | `vibe --agent %s` | %s |\n", dn, slug, desc)
+			w("%s\n", row)
 		}
 	}
 
 	if len(wfRows) > 0 {
-		w("\n## Workflow Shortcut Agents\n\n")
+		w("\n%s Workflow Shortcut Agents\n\n", h)
 		w("| Agent | Command | Description |\n")
 		w("|---|---|---|\n")
 		for _, row := range wfRows {
@@ -697,10 +1062,13 @@ func generateAgentsMD(cfg *config, report *conversionReport) {
 		}
 	}
 
-	path := filepath.Join(cfg.vibeHome, "AGENTS.md")
-	writeFile(cfg, path, b.String(), report)
+	if cfg.sharedIndex() {
+		writeSharedIndex(cfg, b.String(), report)
+	} else {
+		writeFile(cfg, cfg.indexPath(), b.String(), source{}, report)
+	}
 	if cfg.verbose {
-		fmt.Println("   📝 AGENTS.md generated")
+		cfg.printf("   📝 %s generated\n", t.index())
 	}
 }
 
@@ -708,187 +1076,52 @@ func generateAgentsMD(cfg *config, report *conversionReport) {
 
 func validate(cfg *config, report *conversionReport) {
 	if cfg.dryRun {
-		fmt.Println("   (skipped in dry-run)")
+		cfg.println("   (skipped in dry-run)")
 		return
 	}
 
-	agentsDir := filepath.Join(cfg.vibeHome, "agents")
-	promptsDir := filepath.Join(cfg.vibeHome, "prompts")
 	skillsDir := filepath.Join(cfg.vibeHome, "skills")
 
-	tomlFiles, _ := filepath.Glob(filepath.Join(agentsDir, "bmad-*.toml"))
-	promptFiles, _ := filepath.Glob(filepath.Join(promptsDir, "bmad-*.md"))
-
-	// 1. TOML → prompt cross-ref + required fields + valid safety
-	for _, tp := range tomlFiles {
-		data, _ := os.ReadFile(tp)
-		c := string(data)
-		base := filepath.Base(tp)
-
-		pid := extractTOMLVal(c, "system_prompt_id")
-		if pid == "" {
-			report.err(fmt.Sprintf("%s: missing system_prompt_id", base))
-			continue
-		}
-		if !fileExists(filepath.Join(promptsDir, pid+".md")) {
-			report.err(fmt.Sprintf("%s: prompt %s.md not found", base, pid))
-		}
-
-		for _, f := range []string{"display_name", "description", "safety", "enabled_tools"} {
-			if !strings.Contains(c, f+" =") && !strings.Contains(c, f+"=") {
-				report.err(fmt.Sprintf("%s: missing field %q", base, f))
-			}
-		}
+	// 1. Agent files: syntax, fields, prompts, shortcut skills
+	agents, prompts := cfg.target.validateAgents(cfg, report)
 
-		safety := extractTOMLVal(c, "safety")
-		valid := map[string]bool{"safe": true, "neutral": true, "destructive": true, "yolo": true}
-		if !valid[safety] {
-			report.err(fmt.Sprintf("%s: invalid safety %q", base, safety))
-		}
-	}
-
-	// 2. Prompt size
-	for _, p := range promptFiles {
-		info, _ := os.Stat(p)
-		if info != nil && info.Size() < 50 {
-			report.warn(fmt.Sprintf("%s: suspiciously small (%d bytes)", filepath.Base(p), info.Size()))
-		}
-	}
-
-	// 3. Orphaned prompts
-	for _, p := range promptFiles {
-		slug := strings.TrimSuffix(filepath.Base(p), ".md")
-		if !fileExists(filepath.Join(agentsDir, slug+".toml")) {
-			report.warn(fmt.Sprintf("orphaned prompt: %s.md", slug))
-		}
-	}
-
-	// 4. Skill dirs have SKILL.md (except data/docs dirs)
+	// 2. Skill dirs have a SKILL.md (except data/docs dirs) with valid frontmatter
 	if entries, err := os.ReadDir(skillsDir); err == nil {
 		for _, e := range entries {
 			if !e.IsDir() || !strings.HasPrefix(e.Name(), "bmad-") {
 				continue
 			}
-			if !fileExists(filepath.Join(skillsDir, e.Name(), "SKILL.md")) {
-				if !strings.HasSuffix(e.Name(), "-data") && !strings.HasSuffix(e.Name(), "-docs") {
-					report.warn(fmt.Sprintf("skill %s: missing SKILL.md", e.Name()))
+			data, err := os.ReadFile(filepath.Join(skillsDir, e.Name(), "SKILL.md"))
+			if err != nil {
+				if !dataDir(e.Name()) {
+					report.warn(diagMissingSkillMD, "skills/"+e.Name(), "missing SKILL.md")
 				}
+				continue
 			}
+			checkSkillMD("skills/"+e.Name()+"/SKILL.md", e.Name(), string(data), cfg.target.tools(), report)
 		}
 	}
 
-	// 5. Workflow shortcut → skill exists
-	for _, tp := range tomlFiles {
-		data, _ := os.ReadFile(tp)
-		c := string(data)
-		if !strings.Contains(c, "workflow shortcut") {
-			continue
-		}
-		pid := extractTOMLVal(c, "system_prompt_id")
-		pData, _ := os.ReadFile(filepath.Join(promptsDir, pid+".md"))
-		re := regexp.MustCompile("Skill slug: `([^`]+)`")
-		m := re.FindStringSubmatch(string(pData))
-		if len(m) >= 2 && !dirExists(filepath.Join(skillsDir, m[1])) {
-			report.err(fmt.Sprintf("%s: skill %s not found", filepath.Base(tp), m[1]))
-		}
-	}
-
-	// Counts
+	// Counts, like the report: data and docs dirs are not skills
 	skillCount := 0
 	if entries, _ := os.ReadDir(skillsDir); entries != nil {
 		for _, e := range entries {
-			if e.IsDir() && strings.HasPrefix(e.Name(), "bmad-") {
+			if e.IsDir() && strings.HasPrefix(e.Name(), "bmad-") && !dataDir(e.Name()) {
 				skillCount++
 			}
 		}
 	}
-	fmt.Printf("   Agents: %d | Prompts: %d | Skills: %d\n", len(tomlFiles), len(promptFiles), skillCount)
-}
-
-// --- Report ---
-
-func printReport(cfg *config, report *conversionReport) {
-	fmt.Println("\n" + strings.Repeat("═", 60))
-	fmt.Println("📊 Conversion Report")
-	fmt.Println(strings.Repeat("═", 60))
-
-	agents := unique(report.agents)
-	skills := unique(report.skills)
-	sort.Strings(agents)
-	sort.Strings(skills)
-
-	persona := filter(agents, func(s string) bool { return !strings.Contains(s, "(workflow)") })
-	wf := filter(agents, func(s string) bool { return strings.Contains(s, "(workflow)") })
+	cfg.printf("   Agents: %d | Prompts: %d | Skills: %d\n", agents, prompts, skillCount)
 
-	fmt.Printf("\n✅ Persona agents: %d\n", len(persona))
-	for _, a := range persona {
-		fmt.Printf("   • %s\n", a)
-	}
-	fmt.Printf("✅ Workflow agents: %d\n", len(wf))
-
-	fmt.Printf("✅ Skills:          %d\n", len(skills))
-	for _, s := range skills {
-		fmt.Printf("   • %s\n", s)
-	}
-
-	if len(report.warnings) > 0 {
-		fmt.Printf("\n⚠️  Warnings: %d\n", len(report.warnings))
-		for _, w := range report.warnings {
-			fmt.Printf("   ⚠️  %s\n", w)
-		}
-	}
-
-	if len(report.errors) > 0 {
-		fmt.Printf("\n❌ Errors: %d\n", len(report.errors))
-		for _, e := range report.errors {
-			fmt.Printf("   ❌ %s\n", e)
-		}
-		os.Exit(1)
-	}
+	// 3. Referenced install paths exist
+	checkReferences(cfg, report)
 
-	fmt.Println("\n🎉 All checks passed!")
-	fmt.Println("\n📖 Usage:")
-	if len(persona) > 0 {
-		fmt.Printf("  vibe --agent %s\n", persona[0])
-	}
-	fmt.Printf("\n  AGENTS.md: %s/AGENTS.md\n", cfg.vibeHome)
-	fmt.Println("  → Copy to project root for AGENTS.md support")
+	// 4. Token budgets
+	checkBudgets(cfg, report)
 }
 
 // --- Helpers ---
 
-func extractAgentMeta(slug, raw string) agentMeta {
-	return agentMeta{
-		Slug:        slug,
-		Name:        extractXMLAttr(raw, "name"),
-		Title:       extractXMLAttr(raw, "title"),
-		Icon:        extractXMLAttr(raw, "icon"),
-		Description: extractXMLAttr(raw, "description"),
-	}
-}
-
-func extractXMLAttr(raw, attr string) string {
-	tagEnd := strings.Index(raw, ">")
-	if tagEnd == -1 {
-		return ""
-	}
-	re := regexp.MustCompile(fmt.Sprintf(`%s="([^"]*)"`, regexp.QuoteMeta(attr)))
-	m := re.FindStringSubmatch(raw[:tagEnd+1])
-	if len(m) < 2 {
-		return ""
-	}
-	return m[1]
-}
-
-func extractTOMLVal(content, key string) string {
-	re := regexp.MustCompile(fmt.Sprintf(`%s\s*=\s*"([^"]*)"`, regexp.QuoteMeta(key)))
-	m := re.FindStringSubmatch(content)
-	if len(m) < 2 {
-		return ""
-	}
-	return m[1]
-}
-
 func safetyForAgent(slug string) string {
 	if s, ok := agentSafetyMap[slug]; ok {
 		return s
@@ -923,6 +1156,22 @@ func buildSkillSlug(module, rel, name string) string {
 	return slug
 }
 
+func taskSkillSlug(module, name string) string {
+	return fmt.Sprintf("bmad-%s-task-%s", module, strings.TrimSuffix(name, filepath.Ext(name)))
+}
+
+// isWorkflowFile reports whether name is a workflow entry point
+// (workflow.md, workflow.yaml, workflow-<variant>.md, ...).
+func isWorkflowFile(name string) bool {
+	ext := filepath.Ext(name)
+	return strings.HasPrefix(name, "workflow") && (ext == ".md" || ext == ".yaml")
+}
+
+func isTaskFile(name string) bool {
+	ext := filepath.Ext(name)
+	return ext == ".md" || ext == ".xml"
+}
+
 func toTitle(s string) string {
 	words := strings.Split(strings.ReplaceAll(s, "-", " "), " ")
 	for i, w := range words {
@@ -960,13 +1209,46 @@ func collectFiles(dir, extFilter string) []namedContent {
 	return result
 }
 
-// collectStepDirs collects .md files from all subdirectories whose name contains "step".
-func collectStepDirs(dir string) []namedContent {
+// stepGroup is one directory of step files. Workflows with several modes
+// keep one directory per mode (steps-c, steps-e, steps-v for create, edit and
+// validate).
+type stepGroup struct {
+	dir   string
+	steps []namedContent
+}
+
+// stepModes names the modes of the conventional step directory suffixes.
+var stepModes = map[string]string{"c": "Create", "e": "Edit", "v": "Validate"}
+
+// mode returns the display name of the group, "" for a plain steps/ dir.
+func (g stepGroup) mode() string {
+	i := strings.LastIndexAny(g.dir, "-_")
+	if i < 0 {
+		return ""
+	}
+	suffix := strings.ToLower(g.dir[i+1:])
+	if m, ok := stepModes[suffix]; ok {
+		return m
+	}
+	return toTitle(suffix)
+}
+
+// collectStepDirs collects the .md files of every subdirectory whose name
+// contains "step", one group per directory. Steps listed in order (paths
+// relative to dir, as declared by the workflow's steps key) come first in
+// that order; the others follow in natural order, so step-2 precedes step-10.
+func collectStepDirs(dir string, order []string) []stepGroup {
 	entries, err := os.ReadDir(dir)
 	if err != nil {
 		return nil
 	}
-	var result []namedContent
+	rank := make(map[string]int, len(order))
+	for i, o := range order {
+		o = strings.TrimPrefix(strings.TrimSpace(o), "{installed_path}/")
+		rank[path.Clean(strings.TrimPrefix(o, "./"))] = i + 1
+	}
+
+	var groups []stepGroup
 	for _, e := range entries {
 		if !e.IsDir() || !strings.Contains(strings.ToLower(e.Name()), "step") {
 			continue
@@ -975,6 +1257,7 @@ func collectStepDirs(dir string) []namedContent {
 		if err != nil {
 			continue
 		}
+		g := stepGroup{dir: e.Name()}
 		for _, se := range subEntries {
 			if se.IsDir() || !strings.HasSuffix(se.Name(), ".md") {
 				continue
@@ -983,10 +1266,59 @@ func collectStepDirs(dir string) []namedContent {
 			if err != nil {
 				continue
 			}
-			result = append(result, namedContent{name: se.Name(), content: string(data)})
+			g.steps = append(g.steps, namedContent{name: se.Name(), content: string(data)})
+		}
+		if len(g.steps) == 0 {
+			continue
+		}
+		sort.SliceStable(g.steps, func(i, j int) bool {
+			ri, rj := rank[g.dir+"/"+g.steps[i].name], rank[g.dir+"/"+g.steps[j].name]
+			switch {
+			case ri != 0 && rj != 0:
+				return ri < rj
+			case ri != 0 || rj != 0:
+				return ri != 0
+			}
+			return naturalLess(g.steps[i].name, g.steps[j].name)
+		})
+		groups = append(groups, g)
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return naturalLess(groups[i].dir, groups[j].dir) })
+	return groups
+}
+
+// naturalLess compares strings with digit runs ordered by numeric value.
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		da, db := digitPrefix(a), digitPrefix(b)
+		if da != "" && db != "" {
+			na, nb := strings.TrimLeft(da, "0"), strings.TrimLeft(db, "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			if len(da) != len(db) {
+				return len(da) < len(db)
+			}
+			a, b = a[len(da):], b[len(db):]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
 		}
+		a, b = a[1:], b[1:]
 	}
-	return result
+	return len(a) < len(b)
+}
+
+func digitPrefix(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i]
 }
 
 func collectNamedFiles(dir string, substrings ...string) []namedContent {
@@ -1019,30 +1351,65 @@ func collectNamedFiles(dir string, substrings ...string) []namedContent {
 	return result
 }
 
-func writeFile(cfg *config, path, content string, report *conversionReport) {
+// writeFile records path in the run manifest and writes content unless the
+// file on disk already holds exactly that content. Files edited by hand since
+// the previous run are handed to resolveConflict.
+func writeFile(cfg *config, path, content string, src source, report *conversionReport) {
+	rel := cfg.manifestPath(path)
+	entry := cfg.entryFor([]byte(content), src)
+	art := artifact{Kind: artifactKind(rel), Module: src.Module, Repo: entry.Repo, Source: entry.Source, Output: rel, Bytes: len(content), Safety: src.Safety}
+	defer func() { report.artifacts = append(report.artifacts, art) }()
+
+	status := "added"
+	switch disk := fileHash(path); {
+	case disk == entry.Hash:
+		status = "unchanged"
+		report.unchanged = append(report.unchanged, rel)
+	case disk == "":
+		report.added = append(report.added, rel)
+	case cfg.editedSinceGeneration(rel, disk):
+		art.Status = "edited"
+		resolveConflict(cfg, path, content, entry, src, report)
+		return
+	default:
+		status = "updated"
+		report.updated = append(report.updated, rel)
+	}
+	art.Status = status
+	cfg.manifest.Files[rel] = entry
+
 	if cfg.dryRun {
-		fmt.Printf("   [DRY] %s\n", path)
+		cfg.printf("   [DRY] %s (%s)\n", path, status)
+		return
+	}
+	if status == "unchanged" {
+		if !fileExists(cfg.basePath(rel)) {
+			cfg.saveBase(rel, content)
+		}
 		return
 	}
 	os.MkdirAll(filepath.Dir(path), 0o755)
 	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
-		report.err(fmt.Sprintf("write %s: %v", path, err))
+		report.err(diagIO, cfg.manifestPath(path), "write: %v", err)
+		return
 	}
+	cfg.saveBase(rel, content)
 }
 
-func copyDir(src, dest string) error {
+// copyDir copies src into dest through writeFile so copied data is tracked
+// by the manifest like any other output.
+func copyDir(cfg *config, module, src, dest string, report *conversionReport) error {
 	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
+		if err != nil || info.IsDir() {
 			return nil
 		}
 		rel, _ := filepath.Rel(src, path)
-		target := filepath.Join(dest, rel)
-		if info.IsDir() {
-			return os.MkdirAll(target, 0o755)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
 		}
-		data, _ := os.ReadFile(path)
-		os.MkdirAll(filepath.Dir(target), 0o755)
-		return os.WriteFile(target, data, 0o644)
+		writeFile(cfg, filepath.Join(dest, rel), string(data), source{Module: module, Path: path}, report)
+		return nil
 	})
 }
 
@@ -1056,12 +1423,13 @@ func fileExists(path string) bool {
 	return err == nil
 }
 
-func joinQuoted(ss []string) string {
-	q := make([]string, len(ss))
-	for i, s := range ss {
-		q[i] = fmt.Sprintf("%q", s)
+func firstNonEmpty(ss ...string) string {
+	for _, s := range ss {
+		if s != "" {
+			return s
+		}
 	}
-	return strings.Join(q, ", ")
+	return ""
 }
 
 func splitTrim(s, sep string) []string {
@@ -1076,6 +1444,23 @@ func splitTrim(s, sep string) []string {
 	return result
 }
 
+// prefixNames prefixes the name of every file with dir.
+func prefixNames(dir string, files []namedContent) []namedContent {
+	for i := range files {
+		files[i].name = dir + files[i].name
+	}
+	return files
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func unique(ss []string) []string {
 	seen := make(map[string]bool, len(ss))
 	var result []string
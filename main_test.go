@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNaturalLess(t *testing.T) {
+	tests := []struct {
+		a, b string
+		less bool
+	}{
+		{"step-2.md", "step-10.md", true},
+		{"step-10.md", "step-2.md", false},
+		{"step-01.md", "step-1.md", false}, // same value: fewer digits first
+		{"step-1.md", "step-01.md", true},
+		{"step-02b.md", "step-02c.md", true},
+		{"step-3.md", "step-3b.md", true},
+		// No digits: lexical order.
+		{"intro.md", "outro.md", true},
+		{"outro.md", "intro.md", false},
+		{"step.md", "steps.md", true},
+		{"same.md", "same.md", false},
+	}
+	for _, tt := range tests {
+		if got := naturalLess(tt.a, tt.b); got != tt.less {
+			t.Errorf("naturalLess(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.less)
+		}
+	}
+}
+
+func TestCollectStepDirs(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{
+		"steps-v/step-01-validate.md",
+		"steps-c/step-10-finish.md",
+		"steps-c/step-2-draft.md",
+		"steps-c/step-1-init.md",
+		"steps-c/overview.md",
+		"steps-c/notes.txt",
+		"steps-e/step-02-apply.md",
+		"steps-e/step-01-load.md",
+		"steps-e/data/ignored.md",
+		"templates/step-template.md",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(f))
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		os.WriteFile(path, []byte(f), 0o644)
+	}
+
+	summary := func(groups []stepGroup) string {
+		var out []string
+		for _, g := range groups {
+			var names []string
+			for _, s := range g.steps {
+				names = append(names, s.name)
+			}
+			out = append(out, g.mode()+": "+strings.Join(names, " "))
+		}
+		return strings.Join(out, " | ")
+	}
+
+	// Each mode stays one group, in natural order.
+	want := "Create: overview.md step-1-init.md step-2-draft.md step-10-finish.md | Edit: step-01-load.md step-02-apply.md | Validate: step-01-validate.md"
+	if got := summary(collectStepDirs(dir, nil)); got != want {
+		t.Errorf("natural order:\n got %s\nwant %s", got, want)
+	}
+
+	// Declared steps come first, in their declared order.
+	order := []string{"{installed_path}/steps-c/step-10-finish.md", "./steps-c/overview.md"}
+	want = "Create: step-10-finish.md overview.md step-1-init.md step-2-draft.md | Edit: step-01-load.md step-02-apply.md | Validate: step-01-validate.md"
+	if got := summary(collectStepDirs(dir, order)); got != want {
+		t.Errorf("declared order:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestValidateSkillCount(t *testing.T) {
+	home := t.TempDir()
+	skill := "---\nname: %s\ndescription: Does a thing\n---\n\n# Thing\n"
+	writeTree(t, home, map[string]string{
+		"skills/bmad-bmm-prd/SKILL.md":         strings.ReplaceAll(skill, "%s", "bmad-bmm-prd"),
+		"skills/bmad-core-task-shard/SKILL.md": strings.ReplaceAll(skill, "%s", "bmad-core-task-shard"),
+		"skills/bmad-bmm-data/template.md":     "# Template\n",
+		"skills/bmad-bmm-docs/guide.md":        "# Guide\n",
+	})
+	var out bytes.Buffer
+	cfg := &config{vibeHome: home, target: vibeTarget{}, file: &fileConfig{}, tokenizer: defaultTokenizer, out: &out}
+	report := &conversionReport{}
+	validate(cfg, report)
+
+	if !strings.Contains(out.String(), "Skills: 2\n") {
+		t.Errorf("data and docs dirs counted as skills:\n%s", out.String())
+	}
+	if len(report.warnings) > 0 {
+		t.Errorf("warnings: %v", report.warnings)
+	}
+	if got := artifactKind("skills/bmad-bmm-data/sub/template.md"); got != "data" {
+		t.Errorf("artifactKind = %q, want data", got)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// --- Generation manifest ---
+
+const manifestName = ".bmad2vibe-manifest.json"
+
+// manifest records every file bmad2vibe wrote under vibe-home, so later runs
+// can skip unchanged outputs and remove outputs whose source disappeared.
+// Paths are slash-separated and relative to vibe-home.
+type manifest struct {
+	Version int                      `json:"version"`
+	Files   map[string]manifestEntry `json:"files"`
+}
+
+type manifestEntry struct {
+	Hash   string `json:"sha256"`
+	Module string `json:"module,omitempty"`
+	Repo   string `json:"repo,omitempty"`   // source repo name (bmad-bundles, BMAD-METHOD)
+	Source string `json:"source,omitempty"` // source path relative to the repo
+	Commit string `json:"commit,omitempty"` // source repo HEAD at generation time
+}
+
+// source identifies the BMAD file an output was generated from.
+type source struct {
+	Module string
+	Path   string // absolute path on disk, empty for aggregate outputs
+	Safety string // safety level of a generated agent, for the report
+}
+
+func newManifest() *manifest {
+	return &manifest{Version: 1, Files: make(map[string]manifestEntry)}
+}
+
+// loadManifest reads the manifest from vibe-home. A missing file yields an
+// empty manifest; a corrupt one is reported and treated as empty.
+func loadManifest(vibeHome string) (*manifest, error) {
+	data, err := os.ReadFile(filepath.Join(vibeHome, manifestName))
+	if os.IsNotExist(err) {
+		return newManifest(), nil
+	}
+	if err != nil {
+		return newManifest(), err
+	}
+	m := newManifest()
+	if err := json.Unmarshal(data, m); err != nil {
+		return newManifest(), fmt.Errorf("%s: %v", manifestName, err)
+	}
+	if m.Files == nil {
+		m.Files = make(map[string]manifestEntry)
+	}
+	return m, nil
+}
+
+func (m *manifest) save(vibeHome string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(vibeHome, manifestName), append(data, '\n'), 0o644)
+}
+
+// paths returns the manifest paths in sorted order.
+func (m *manifest) paths() []string {
+	paths := make([]string, 0, len(m.Files))
+	for p := range m.Files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func hashContent(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// fileHash returns the hash of the file at path, or "" if it cannot be read.
+func fileHash(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return hashContent(data)
+}
+
+// manifestPath converts an absolute output path to its manifest key.
+func (cfg *config) manifestPath(path string) string {
+	rel, err := filepath.Rel(cfg.vibeHome, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// entryFor builds the manifest entry for content generated from src.
+func (cfg *config) entryFor(content []byte, src source) manifestEntry {
+	e := manifestEntry{Hash: hashContent(content), Module: src.Module}
+	if r, rel := cfg.sourceRepo(src.Path); r != nil {
+		e.Repo, e.Source, e.Commit = r.Name, rel, r.SHA
+	}
+	return e
+}
+
+// sourceRepo returns the source repository holding path and the path
+// relative to it, or nil when path is outside both checkouts.
+func (cfg *config) sourceRepo(path string) (*sourceRepo, string) {
+	for _, r := range []*sourceRepo{&cfg.bundles, &cfg.method} {
+		if r.Dir == "" || path == "" {
+			continue
+		}
+		if rel, err := filepath.Rel(r.Dir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return r, filepath.ToSlash(rel)
+		}
+	}
+	return nil, ""
+}
+
+// sourceFile names a source file in reports: relative to its repository
+// when it is in one.
+func (cfg *config) sourceFile(path string) string {
+	if _, rel := cfg.sourceRepo(path); rel != "" {
+		return rel
+	}
+	return path
+}
+
+// generated reports whether path was already written during this run.
+func (cfg *config) generated(path string) bool {
+	_, ok := cfg.manifest.Files[cfg.manifestPath(path)]
+	return ok
+}
+
+// removeStale deletes outputs recorded by the previous run for the converted
+// modules that were not regenerated this time. Files edited since they were
+// generated are kept and reported. Aggregate outputs without a module
+// (AGENTS.md) are always regenerated and never considered stale.
+func removeStale(cfg *config, report *conversionReport) {
+	converted := make(map[string]bool, len(cfg.modules))
+	for _, m := range cfg.modules {
+		converted[m] = true
+	}
+
+	for _, rel := range cfg.prevManifest.paths() {
+		prev := cfg.prevManifest.Files[rel]
+		if _, ok := cfg.manifest.Files[rel]; ok || prev.Module == "" {
+			continue
+		}
+		// Outputs of modules not converted this run are carried over untouched.
+		if !converted[prev.Module] {
+			cfg.manifest.Files[rel] = prev
+			continue
+		}
+
+		path := filepath.Join(cfg.vibeHome, filepath.FromSlash(rel))
+		switch fileHash(path) {
+		case "":
+			continue // already gone
+		case prev.Hash:
+			if cfg.dryRun {
+				cfg.printf("   [DRY] Would remove %s\n", path)
+				break
+			}
+			if err := os.Remove(path); err != nil {
+				report.warn(diagIO, rel, "remove stale output: %v", err)
+				continue
+			}
+			removeEmptyParents(filepath.Dir(path), cfg.vibeHome)
+			cfg.removeBase(rel)
+		default:
+			report.warn(diagEditedOutput, rel, "stale output edited since generation — kept")
+			continue
+		}
+		report.removed = append(report.removed, rel)
+	}
+}
+
+// removeEmptyParents removes dir and its ancestors up to (excluding) root
+// while they are empty.
+func removeEmptyParents(dir, root string) {
+	for dir != root && strings.HasPrefix(dir, root) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRemoveStale(t *testing.T) {
+	files := map[string]string{
+		"skills/bmad-bmm-old/SKILL.md":        "generated",
+		"skills/bmad-bmm-old/step-1.md":       "generated step",
+		"skills/bmad-bmm-edited/SKILL.md":     "generated\nplus my notes\n",
+		"skills/bmad-bmm-current/SKILL.md":    "regenerated",
+		"skills/bmad-cis-brainstorm/SKILL.md": "other module",
+		"AGENTS.md":                           "index",
+	}
+	prev := newManifest()
+	for rel, content := range files {
+		prev.Files[rel] = manifestEntry{Hash: hashContent([]byte(content)), Module: "bmm"}
+	}
+	prev.Files["skills/bmad-bmm-edited/SKILL.md"] = manifestEntry{Hash: hashContent([]byte("generated\n")), Module: "bmm"}
+	prev.Files["skills/bmad-cis-brainstorm/SKILL.md"] = manifestEntry{Hash: hashContent([]byte("other module")), Module: "cis"}
+	prev.Files["AGENTS.md"] = manifestEntry{Hash: hashContent([]byte("index"))}
+	prev.Files["skills/bmad-bmm-deleted/SKILL.md"] = manifestEntry{Hash: hashContent([]byte("gone")), Module: "bmm"}
+
+	setup := func(t *testing.T, dryRun bool) (*config, *conversionReport) {
+		home := t.TempDir()
+		writeTree(t, home, files)
+		cfg := &config{vibeHome: home, modules: []string{"bmm"}, dryRun: dryRun, out: io.Discard, prevManifest: prev, manifest: newManifest()}
+		cfg.manifest.Files["skills/bmad-bmm-current/SKILL.md"] = manifestEntry{Hash: hashContent([]byte("regenerated")), Module: "bmm"}
+		cfg.saveBase("skills/bmad-bmm-old/SKILL.md", "generated")
+		report := &conversionReport{}
+		removeStale(cfg, report)
+		return cfg, report
+	}
+	exists := func(cfg *config, rel string) bool {
+		_, err := os.Stat(filepath.Join(cfg.vibeHome, filepath.FromSlash(rel)))
+		return err == nil
+	}
+
+	t.Run("remove", func(t *testing.T) {
+		cfg, report := setup(t, false)
+		wantRemoved := []string{"skills/bmad-bmm-old/SKILL.md", "skills/bmad-bmm-old/step-1.md"}
+		if !reflect.DeepEqual(report.removed, wantRemoved) {
+			t.Errorf("removed %v, want %v", report.removed, wantRemoved)
+		}
+		for _, rel := range []string{"skills/bmad-bmm-old", baseDir + "/skills/bmad-bmm-old"} {
+			if exists(cfg, rel) {
+				t.Errorf("%s left behind", rel)
+			}
+		}
+
+		// Hand-edited stale files are kept and reported.
+		if !exists(cfg, "skills/bmad-bmm-edited/SKILL.md") {
+			t.Error("edited stale file removed")
+		}
+		if len(report.warnings) != 1 || report.warnings[0].Code != diagEditedOutput.ID || report.warnings[0].File != "skills/bmad-bmm-edited/SKILL.md" {
+			t.Errorf("warnings = %v", report.warnings)
+		}
+
+		// Regenerated outputs, aggregates and other modules are left alone;
+		// the latter stay in the manifest.
+		for _, rel := range []string{"skills/bmad-bmm-current/SKILL.md", "skills/bmad-cis-brainstorm/SKILL.md", "AGENTS.md"} {
+			if !exists(cfg, rel) {
+				t.Errorf("%s removed", rel)
+			}
+		}
+		if _, ok := cfg.manifest.Files["skills/bmad-cis-brainstorm/SKILL.md"]; !ok {
+			t.Error("output of an unconverted module dropped from the manifest")
+		}
+		if _, ok := cfg.manifest.Files["skills/bmad-bmm-old/SKILL.md"]; ok {
+			t.Error("removed output still in the manifest")
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		cfg, report := setup(t, true)
+		if len(report.removed) != 2 || !exists(cfg, "skills/bmad-bmm-old/SKILL.md") {
+			t.Errorf("dry run: removed %v", report.removed)
+		}
+	})
+}
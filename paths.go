@@ -0,0 +1,193 @@
+package main
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// --- Installed paths ---
+//
+// Generated prompts and skills tell the model which files to read. Those
+// references must point at the actual install location, written the way
+// the model will see them from Vibe's working directory.
+
+// vibePaths renders references to files under vibe-home.
+type vibePaths struct {
+	home string // vibe-home as written in prompts: "~/.vibe", ".vibe", or absolute
+	disk string // vibe-home on disk
+
+	skills skillIndex // converted workflows and tasks, for render
+}
+
+// newVibePaths chooses how to write vibe-home: relative to the project root
+// for project installs inside it, under ~ when in the user's home, otherwise
+// absolute.
+func newVibePaths(vibeHome, scope, projectRoot string) vibePaths {
+	p := vibePaths{disk: vibeHome}
+	abs, err := filepath.Abs(vibeHome)
+	if err != nil {
+		abs = vibeHome
+	}
+	p.home = filepath.ToSlash(abs)
+	if scope == scopeProject {
+		if rel, ok := within(projectRoot, abs); ok {
+			p.home = rel
+			return p
+		}
+	}
+	if userHome, err := os.UserHomeDir(); err == nil {
+		if rel, ok := within(userHome, abs); ok {
+			p.home = "~/" + rel
+		}
+	}
+	return p
+}
+
+// within returns path relative to dir if path is strictly inside dir.
+func within(dir, path string) (string, bool) {
+	if dir == "" {
+		return "", false
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// skill returns the reference to the directory of a skill.
+func (p vibePaths) skill(slug string) string {
+	return p.home + "/skills/" + slug
+}
+
+// dataDir reports whether name, a directory under skills/, holds the copied
+// data or docs of a module rather than a skill.
+func dataDir(name string) bool {
+	return strings.HasSuffix(name, "-data") || strings.HasSuffix(name, "-docs")
+}
+
+// bmadRefPattern matches references to the files of an installed BMAD
+// module: {project-root}/_bmad/<module>/<kind>/<path>.
+var bmadRefPattern = regexp.MustCompile(`\{project-root\}/_bmad/([\w-]+)/(data|docs|workflows|tasks)/([^\s` + "`" + `'"()\[\]<>|*]+)`)
+
+// render rewrites references to BMAD module files to where bmad2vibe
+// installs them: data and docs under skills/bmad-<module>-data and -docs,
+// workflow and task entry points to the SKILL.md of their skill, and other
+// workflow files to the skill directory. It returns the references it could
+// not map; those are left as they are.
+func (p vibePaths) render(s string) (string, []string) {
+	var unresolved []string
+	out := bmadRefPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		trimmed := strings.TrimRight(ref, ".,;:")
+		if strings.Contains(trimmed[len("{project-root}"):], "{") {
+			return ref // depends on a runtime placeholder
+		}
+		m := bmadRefPattern.FindStringSubmatch(trimmed)
+		mod, kind, rest := m[1], m[2], m[3]
+		var to string
+		switch kind {
+		case "data", "docs":
+			to = p.skill("bmad-"+mod+"-"+kind) + "/" + rest
+		case "tasks":
+			if slug, ok := p.skills[mod+"/tasks/"+rest]; ok {
+				to = p.skill(slug) + "/SKILL.md"
+			}
+		case "workflows":
+			to = p.workflowRef(mod + "/workflows/" + rest)
+		}
+		if to == "" {
+			unresolved = append(unresolved, trimmed)
+			return ref
+		}
+		return to + ref[len(trimmed):]
+	})
+	return out, unique(unresolved)
+}
+
+// workflowRef maps a workflow file or directory, relative to src/, into the
+// skill of the innermost workflow holding it, or returns "".
+func (p vibePaths) workflowRef(rel string) string {
+	if slug, ok := p.skills[rel]; ok {
+		return p.skill(slug) + "/SKILL.md"
+	}
+	rel = strings.TrimSuffix(rel, "/") + "/"
+	best, slug := "", ""
+	for _, k := range sortedKeys(p.skills) {
+		dir := path.Dir(k) + "/"
+		if strings.Contains(k, "/workflows/") && strings.HasPrefix(rel, dir) && len(dir) > len(best) {
+			best, slug = dir, p.skills[k]
+		}
+	}
+	if slug == "" {
+		return ""
+	}
+	return strings.TrimSuffix(p.skill(slug)+"/"+strings.TrimPrefix(rel, best), "/")
+}
+
+// renderRefs renders the BMAD references of content, written to file, and
+// reports those that do not map to a converted file. Refs in skip are
+// reported elsewhere.
+func (cfg *config) renderRefs(file, content string, report *conversionReport, skip ...string) string {
+	out, unresolved := cfg.paths.render(content)
+	unresolved = filter(unresolved, func(r string) bool { return !slices.Contains(skip, r) })
+	if len(unresolved) > 0 {
+		sort.Strings(unresolved)
+		report.warn(diagUnconvertedReference, cfg.manifestPath(file), "references BMAD files that are not converted: %s", strings.Join(unresolved, ", "))
+	}
+	return out
+}
+
+// refPattern finds references to vibe-home in generated text: the rendered
+// home at the start of a path, followed by the path up to a delimiter.
+func (p vibePaths) refPattern() *regexp.Regexp {
+	return regexp.MustCompile(`(?:^|[^\w~/.-])(` + regexp.QuoteMeta(p.home) + `/[^\s` + "`" + `'")\]|<>*]+)`)
+}
+
+// onDisk maps a rendered reference back to its file on disk.
+func (p vibePaths) onDisk(ref string) string {
+	return filepath.Join(p.disk, filepath.FromSlash(strings.TrimPrefix(ref, p.home+"/")))
+}
+
+// checkReferences reports vibe-home paths mentioned in generated prompts and
+// skills that do not exist. Paths with unresolved placeholders are skipped.
+func checkReferences(cfg *config, report *conversionReport) {
+	re := cfg.paths.refPattern()
+	files := cfg.target.prompts(cfg.vibeHome)
+	skillsDir := filepath.Join(cfg.vibeHome, "skills")
+	filepath.Walk(skillsDir, func(path string, info os.FileInfo, err error) error {
+		switch {
+		case err != nil:
+			return nil
+		case info.IsDir() && filepath.Dir(path) == skillsDir && dataDir(info.Name()):
+			return filepath.SkipDir // copied BMAD data, not generated text
+		case !info.IsDir() && strings.HasSuffix(path, ".md"):
+			files = append(files, path)
+		}
+		return nil
+	})
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		rel := cfg.manifestPath(f)
+		seen := make(map[string]bool)
+		for i, line := range strings.Split(string(data), "\n") {
+			for _, m := range re.FindAllStringSubmatch(line, -1) {
+				ref := strings.TrimRight(m[1], ".,;:")
+				if seen[ref] || strings.Contains(ref, "{") {
+					continue
+				}
+				seen[ref] = true
+				if !fileExists(cfg.paths.onDisk(ref)) {
+					report.errAt(diagMissingReference, rel, i+1, "references %s, which does not exist", ref)
+				}
+			}
+		}
+	}
+}
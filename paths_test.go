@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	p := vibePaths{home: "~/.vibe", skills: skillIndex{
+		"bmm/workflows/4-impl/dev-story/workflow.yaml": "bmad-bmm-4-impl-dev-story",
+		"bmm/workflows/2-plan/prd/workflow.md":         "bmad-bmm-2-plan-prd",
+		"bmm/workflows/2-plan/prd/sub/workflow.md":     "bmad-bmm-2-plan-prd-sub",
+		"core/tasks/workflow.xml":                      "bmad-core-task-workflow",
+	}}
+	tests := []struct{ in, want string }{
+		{"{project-root}/_bmad/bmm/data/project-types.csv", "~/.vibe/skills/bmad-bmm-data/project-types.csv"},
+		{"{project-root}/_bmad/cis/docs/guide.md", "~/.vibe/skills/bmad-cis-docs/guide.md"},
+		{`workflow="{project-root}/_bmad/bmm/workflows/4-impl/dev-story/workflow.yaml"`, `workflow="~/.vibe/skills/bmad-bmm-4-impl-dev-story/SKILL.md"`},
+		{"LOAD {project-root}/_bmad/core/tasks/workflow.xml.", "LOAD ~/.vibe/skills/bmad-core-task-workflow/SKILL.md."},
+		{"`{project-root}/_bmad/bmm/workflows/2-plan/prd/steps/step-01.md`", "`~/.vibe/skills/bmad-bmm-2-plan-prd/steps/step-01.md`"},
+		{"{project-root}/_bmad/bmm/workflows/2-plan/prd/sub/data/x.csv", "~/.vibe/skills/bmad-bmm-2-plan-prd-sub/data/x.csv"},
+		{"{project-root}/_bmad/bmm/workflows/4-impl/dev-story", "~/.vibe/skills/bmad-bmm-4-impl-dev-story"},
+		// Left alone: not module files, or placeholders resolved at run time.
+		{"{project-root}/_bmad/bmm/config.yaml", "{project-root}/_bmad/bmm/config.yaml"},
+		{"{project-root}/_bmad/bmm/data/{project_type}.csv", "{project-root}/_bmad/bmm/data/{project_type}.csv"},
+	}
+	for _, tt := range tests {
+		got, unresolved := p.render(tt.in)
+		if got != tt.want || len(unresolved) > 0 {
+			t.Errorf("render(%q) = %q, %v; want %q", tt.in, got, unresolved, tt.want)
+		}
+	}
+
+	in := "see {project-root}/_bmad/bmm/workflows/gone/workflow.yaml and {project-root}/_bmad/core/tasks/gone.xml, twice: {project-root}/_bmad/core/tasks/gone.xml"
+	got, unresolved := p.render(in)
+	if got != in {
+		t.Errorf("unresolved references rewritten: %q", got)
+	}
+	if strings.Join(unresolved, " ") != "{project-root}/_bmad/bmm/workflows/gone/workflow.yaml {project-root}/_bmad/core/tasks/gone.xml" {
+		t.Errorf("unresolved = %v", unresolved)
+	}
+}
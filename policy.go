@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// --- Safety policy ---
+
+// policy is the effective safety configuration of one generated agent.
+type policy struct {
+	Safety      string
+	Tools       []string
+	AutoApprove bool
+	Origin      string // where the safety level came from, for verbose output
+}
+
+// policyOverride is a partial policy from the config file; unset fields keep
+// the value of the layer below.
+type policyOverride struct {
+	Safety      string
+	Tools       []string
+	AutoApprove *bool
+}
+
+// workflowRule applies a policy override to workflows whose skill slug
+// matches the glob.
+type workflowRule struct {
+	Match string
+	policyOverride
+}
+
+var validSafety = map[string]bool{"safe": true, "neutral": true, "destructive": true, "yolo": true}
+
+// agentPolicy resolves the policy of a persona agent. Layers, lowest first:
+// built-in defaults, [modules.<module>], [agents.<slug>], [agents."<module>/<slug>"].
+func agentPolicy(cfg *config, module, slug string) policy {
+	p := policy{Safety: safetyForAgent(slug), Origin: "built-in"}
+	var autoApprove *bool
+	apply := func(o policyOverride, origin string) {
+		if o.Safety != "" {
+			p.Safety, p.Origin = o.Safety, origin
+		}
+		if o.Tools != nil {
+			p.Tools = o.Tools
+		}
+		if o.AutoApprove != nil {
+			autoApprove = o.AutoApprove
+		}
+	}
+	if o, ok := cfg.file.Modules[module]; ok {
+		apply(o, fmt.Sprintf("modules.%s", module))
+	}
+	if o, ok := cfg.file.Agents[slug]; ok {
+		apply(o, fmt.Sprintf("agents.%s", slug))
+	}
+	if o, ok := cfg.file.Agents[module+"/"+slug]; ok {
+		apply(o, fmt.Sprintf("agents.%q", module+"/"+slug))
+	}
+	return cfg.finishPolicy(p, autoApprove, p.Safety == "safe")
+}
+
+// workflowPolicy resolves the policy of a workflow shortcut agent. Layers,
+// lowest first: the built-in name heuristic, [modules.<module>], and the first
+// [[workflows]] rule matching the skill slug.
+func workflowPolicy(cfg *config, module, skillSlug, shortName string) policy {
+	p := policy{Safety: workflowSafety(shortName), Origin: "built-in heuristic"}
+	var autoApprove *bool
+	apply := func(o policyOverride, origin string) {
+		if o.Safety != "" {
+			p.Safety, p.Origin = o.Safety, origin
+		}
+		if o.Tools != nil {
+			p.Tools = o.Tools
+		}
+		if o.AutoApprove != nil {
+			autoApprove = o.AutoApprove
+		}
+	}
+	if o, ok := cfg.file.Modules[module]; ok {
+		apply(o, fmt.Sprintf("modules.%s", module))
+	}
+	for i, r := range cfg.file.Workflows {
+		if matchGlob(r.Match, skillSlug) {
+			apply(r.policyOverride, fmt.Sprintf("workflows[%d] %q", i, r.Match))
+			break
+		}
+	}
+	return cfg.finishPolicy(p, autoApprove, p.Safety != "destructive")
+}
+
+// finishPolicy fills the tool list from the safety level and the auto-approve
+// flag from its default when the config did not set them.
+func (cfg *config) finishPolicy(p policy, autoApprove *bool, defaultAutoApprove bool) policy {
+	if p.Tools == nil {
+		p.Tools = cfg.toolsFor(p.Safety)
+	}
+	p.AutoApprove = defaultAutoApprove
+	if autoApprove != nil {
+		p.AutoApprove = *autoApprove
+	}
+	return p
+}
+
+// toolsFor returns the tools enabled for a safety level: [tools] from the
+// config, else the built-in safetyToolsMap.
+func (cfg *config) toolsFor(safety string) []string {
+	if t, ok := cfg.file.Tools[safety]; ok {
+		return t
+	}
+	return safetyToolsMap[safety]
+}
+
+func (p policy) String() string {
+	return fmt.Sprintf("safety=%s auto_approve=%v tools=[%s] (%s)", p.Safety, p.AutoApprove, strings.Join(p.Tools, ", "), p.Origin)
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// --- Conversion report ---
+
+type conversionReport struct {
+	agents    []string
+	prompts   []string
+	skills    []string
+	artifacts []artifact
+
+	policy     diagPolicy
+	warnings   []diagnostic
+	errors     []diagnostic
+	suppressed []diagnostic // matched an ignore rule
+
+	added     []string
+	updated   []string
+	unchanged []string
+	removed   []string
+	conflicts []string // outputs edited by hand since the previous run
+}
+
+// artifact is one file written (or left unchanged) by the run.
+type artifact struct {
+	Kind   string `json:"kind"` // agent, prompt, skill, skill-file, data, index
+	Module string `json:"module,omitempty"`
+	Source string `json:"source,omitempty"` // relative to its repository
+	Repo   string `json:"repo,omitempty"`
+	Output string `json:"output"` // relative to vibe-home
+	Bytes  int    `json:"bytes"`
+	Safety string `json:"safety,omitempty"` // agents and their prompts
+	Status string `json:"status"`           // added, updated, unchanged or edited
+}
+
+// artifactKind classifies an output by its path relative to vibe-home.
+func artifactKind(rel string) string {
+	switch {
+	case rel == "AGENTS.md" || rel == "CLAUDE.md":
+		return "index"
+	case strings.HasPrefix(rel, "agents/"):
+		return "agent"
+	case strings.HasPrefix(rel, "prompts/"):
+		return "prompt"
+	case strings.HasPrefix(rel, "skills/") && path.Base(rel) == "SKILL.md":
+		return "skill"
+	case strings.HasPrefix(rel, "skills/") && dataDir(strings.Split(rel, "/")[1]):
+		return "data"
+	}
+	return "skill-file"
+}
+
+// jsonReport is the -report-format json document.
+type jsonReport struct {
+	Version   int          `json:"version"`
+	OK        bool         `json:"ok"` // no errors
+	Target    string       `json:"target"`
+	VibeHome  string       `json:"vibe_home"`
+	DryRun    bool         `json:"dry_run"`
+	Modules   []string     `json:"modules"`
+	Sources   []jsonSource `json:"sources"`
+	Artifacts []artifact   `json:"artifacts"`
+	Removed   []string     `json:"removed"`
+	Warnings  []diagnostic `json:"warnings"`
+	Errors    []diagnostic `json:"errors"`
+	Summary   jsonSummary  `json:"summary"`
+}
+
+type jsonSource struct {
+	Name   string `json:"name"`
+	URL    string `json:"url,omitempty"`
+	Path   string `json:"path,omitempty"` // local directory, instead of URL
+	Ref    string `json:"ref,omitempty"`
+	Commit string `json:"commit,omitempty"`
+}
+
+type jsonSummary struct {
+	Agents     int `json:"agents"`
+	Prompts    int `json:"prompts"`
+	Skills     int `json:"skills"`
+	Added      int `json:"added"`
+	Updated    int `json:"updated"`
+	Unchanged  int `json:"unchanged"`
+	Removed    int `json:"removed"`
+	Edited     int `json:"edited"`
+	Warnings   int `json:"warnings"`
+	Errors     int `json:"errors"`
+	Suppressed int `json:"suppressed"`
+}
+
+// targetRun is the conversion for one target.
+type targetRun struct {
+	cfg    *config
+	report *conversionReport
+}
+
+// writeJSONReport writes one document per run: an object for a single
+// target, an array for several.
+func writeJSONReport(w io.Writer, runs []targetRun) error {
+	var docs []jsonReport
+	for _, r := range runs {
+		docs = append(docs, newJSONReport(r.cfg, r.report))
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if len(docs) == 1 {
+		return enc.Encode(docs[0])
+	}
+	return enc.Encode(docs)
+}
+
+func newJSONReport(cfg *config, report *conversionReport) jsonReport {
+	nonNil := func(s []string) []string {
+		if s == nil {
+			return []string{}
+		}
+		return s
+	}
+	doc := jsonReport{
+		Version:   1,
+		OK:        len(report.errors) == 0,
+		Target:    cfg.target.name(),
+		VibeHome:  cfg.vibeHome,
+		DryRun:    cfg.dryRun,
+		Modules:   nonNil(cfg.modules),
+		Artifacts: report.artifacts,
+		Removed:   nonNil(report.removed),
+		Warnings:  report.warnings,
+		Errors:    report.errors,
+		Summary: jsonSummary{
+			Agents:     len(unique(report.agents)),
+			Prompts:    len(unique(report.prompts)),
+			Skills:     len(unique(report.skills)),
+			Added:      len(report.added),
+			Updated:    len(report.updated),
+			Unchanged:  len(report.unchanged),
+			Removed:    len(report.removed),
+			Edited:     len(report.conflicts),
+			Warnings:   len(report.warnings),
+			Errors:     len(report.errors),
+			Suppressed: len(report.suppressed),
+		},
+	}
+	for _, r := range []*sourceRepo{&cfg.bundles, &cfg.method} {
+		src := jsonSource{Name: r.Name, URL: r.URL, Ref: r.Ref, Commit: r.SHA}
+		if r.Local {
+			src.URL = ""
+			src.Path, _ = filepath.Abs(r.Dir)
+		}
+		doc.Sources = append(doc.Sources, src)
+	}
+	if doc.Artifacts == nil {
+		doc.Artifacts = []artifact{}
+	}
+	if doc.Warnings == nil {
+		doc.Warnings = []diagnostic{}
+	}
+	if doc.Errors == nil {
+		doc.Errors = []diagnostic{}
+	}
+	return doc
+}
+
+// writeReportFile writes the report in format to path, or to w when path
+// is empty.
+func writeReportFile(w io.Writer, path, format string, runs []targetRun) error {
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if format == "json" {
+		return writeJSONReport(w, runs)
+	}
+	for _, r := range runs {
+		printReport(w, r.cfg, r.report)
+	}
+	return nil
+}
+
+// printReport writes the human-readable report.
+func printReport(w io.Writer, cfg *config, report *conversionReport) {
+	p := func(f string, a ...any) { fmt.Fprintf(w, f, a...) }
+
+	p("\n%s\n", strings.Repeat("═", 60))
+	p("📊 Conversion Report — %s\n", cfg.target.product())
+	p("%s\n", strings.Repeat("═", 60))
+
+	agents := unique(report.agents)
+	skills := unique(report.skills)
+	sort.Strings(agents)
+	sort.Strings(skills)
+
+	persona := filter(agents, func(s string) bool { return !strings.Contains(s, "(workflow)") })
+	wf := filter(agents, func(s string) bool { return strings.Contains(s, "(workflow)") })
+
+	p("\n✅ Persona agents: %d\n", len(persona))
+	for _, a := range persona {
+		p("   • %s\n", a)
+	}
+	p("✅ Workflow agents: %d\n", len(wf))
+
+	p("✅ Skills:          %d\n", len(skills))
+	for _, s := range skills {
+		p("   • %s\n", s)
+	}
+
+	p("\n🔄 Changes: %d added, %d updated, %d unchanged, %d removed, %d edited by hand\n",
+		len(report.added), len(report.updated), len(report.unchanged), len(report.removed), len(report.conflicts))
+	if cfg.verbose {
+		for _, a := range report.added {
+			p("   + %s\n", a)
+		}
+		for _, u := range report.updated {
+			p("   ~ %s\n", u)
+		}
+	}
+	for _, r := range report.removed {
+		p("   - %s\n", r)
+	}
+
+	if len(report.warnings) > 0 {
+		p("\n⚠️  Warnings: %d\n", len(report.warnings))
+		for _, i := range report.warnings {
+			p("   ⚠️  %s\n", i)
+		}
+	}
+
+	if len(report.suppressed) > 0 {
+		p("\n🔕 Suppressed: %d (%s)\n", len(report.suppressed), report.ignoredCodes())
+	}
+
+	if len(report.errors) > 0 {
+		p("\n❌ Errors: %d\n", len(report.errors))
+		for _, i := range report.errors {
+			p("   ❌ %s\n", i)
+		}
+		return
+	}
+
+	p("\n🎉 All checks passed!\n")
+	p("\n📖 Usage:\n")
+	if len(persona) > 0 {
+		p("  %s\n", cfg.target.launch(persona[0]))
+	}
+	if !cfg.writesIndex() {
+		return
+	}
+	p("\n  %s: %s\n", cfg.target.index(), cfg.indexPath())
+	if !cfg.sharedIndex() {
+		p("  → Copy to project root for %s support\n", cfg.target.index())
+	}
+}
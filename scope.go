@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// --- Installation scope ---
+//
+// Global installs go to ~/.vibe (or the target's directory) and serve every
+// project. Project installs go to ./.vibe in the current directory, next to
+// the code they are used with, and maintain a section of the project's own
+// AGENTS.md (or the target's index file).
+
+const (
+	scopeGlobal  = "global"
+	scopeProject = "project"
+)
+
+// resolveScope returns the install directory and, for project installs, the
+// project root. dir is the target's directory name (".vibe"); vibeHome
+// overrides the default location of either scope.
+func resolveScope(scope, vibeHome, dir string) (home, root string, err error) {
+	switch scope {
+	case scopeGlobal, "":
+		if vibeHome == "" {
+			userHome, err := os.UserHomeDir()
+			if err != nil {
+				return "", "", fmt.Errorf("cannot determine home directory: %v", err)
+			}
+			vibeHome = filepath.Join(userHome, dir)
+		}
+		return vibeHome, "", nil
+	case scopeProject:
+		root, err := os.Getwd()
+		if err != nil {
+			return "", "", fmt.Errorf("cannot determine project root: %v", err)
+		}
+		if vibeHome == "" {
+			vibeHome = filepath.Join(root, dir)
+		}
+		return vibeHome, root, nil
+	}
+	return "", "", fmt.Errorf("invalid -scope %q (want global or project)", scope)
+}
+
+// indexPath is where the index (AGENTS.md) goes: the project root for
+// project installs, the install directory otherwise.
+func (cfg *config) indexPath() string {
+	if cfg.scope == scopeProject {
+		return filepath.Join(cfg.projectRoot, cfg.target.index())
+	}
+	return filepath.Join(cfg.vibeHome, cfg.target.index())
+}
+
+// sharedIndex reports whether the index is a section of a file the user
+// owns, rather than a generated file.
+func (cfg *config) sharedIndex() bool {
+	return cfg.target.sharedIndex(cfg.scope)
+}
+
+// writesIndex reports whether the run maintains the index. Global indexes
+// that every project loads are only written with -global-index.
+func (cfg *config) writesIndex() bool {
+	return cfg.scope == scopeProject || cfg.globalIndex || !cfg.target.globalIndexOptIn()
+}
+
+// hasIndexSection reports whether the index holds a bmad2vibe section.
+func (cfg *config) hasIndexSection() bool {
+	data, err := os.ReadFile(cfg.indexPath())
+	if err != nil {
+		return false
+	}
+	_, _, ok := cutAgentsMDSection(string(data))
+	return ok
+}
+
+// Markers around the bmad2vibe section of a shared index.
+const (
+	agentsMDBegin = "<!-- bmad2vibe:begin — generated section, edits inside are overwritten -->"
+	agentsMDEnd   = "<!-- bmad2vibe:end -->"
+)
+
+// mergeAgentsMD replaces the bmad2vibe section of an existing AGENTS.md with
+// section, or appends it when there is none. The rest of the file is kept
+// as is.
+func mergeAgentsMD(existing, section string) string {
+	block := agentsMDBegin + "\n" + strings.TrimRight(section, "\n") + "\n" + agentsMDEnd + "\n"
+	if before, after, ok := cutAgentsMDSection(existing); ok {
+		return before + block + after
+	}
+	if strings.TrimSpace(existing) == "" {
+		return block
+	}
+	return strings.TrimRight(existing, "\n") + "\n\n" + block
+}
+
+// cutAgentsMDSection splits content around its bmad2vibe section, markers
+// and the line break after the end marker included. The section starts at
+// the begin marker closest to the end marker, so a stray begin marker is
+// left to the user.
+func cutAgentsMDSection(content string) (before, after string, ok bool) {
+	j := strings.Index(content, agentsMDEnd)
+	if j < 0 {
+		return "", "", false
+	}
+	i := strings.LastIndex(content[:j], agentsMDBegin)
+	if i < 0 {
+		return "", "", false
+	}
+	end := j + len(agentsMDEnd)
+	if strings.HasPrefix(content[end:], "\n") {
+		end++
+	}
+	return content[:i], content[end:], true
+}
+
+// writeSharedIndex merges section into a shared index such as the project
+// AGENTS.md. The file belongs to the user, so it is not tracked by the
+// manifest: only the marked section is ever rewritten.
+func writeSharedIndex(cfg *config, section string, report *conversionReport) {
+	path := cfg.indexPath()
+	rel := cfg.manifestPath(path)
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		report.err(diagIO, rel, "read: %v", err)
+		return
+	}
+	content := mergeAgentsMD(string(existing), section)
+
+	status := "updated"
+	switch {
+	case err != nil:
+		status = "added"
+		report.added = append(report.added, rel)
+	case content == string(existing):
+		status = "unchanged"
+		report.unchanged = append(report.unchanged, rel)
+	default:
+		report.updated = append(report.updated, rel)
+	}
+	report.artifacts = append(report.artifacts, artifact{Kind: "index", Output: rel, Bytes: len(content), Status: status})
+	if status == "unchanged" {
+		return
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		report.err(diagIO, rel, "write: %v", err)
+	}
+}
+
+// removeSharedIndex strips the bmad2vibe section from a shared index,
+// deleting the file if nothing else is left.
+func removeSharedIndex(cfg *config, report *conversionReport) {
+	path := cfg.indexPath()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	before, after, ok := cutAgentsMDSection(string(data))
+	if !ok {
+		return
+	}
+	rest := strings.TrimRight(before, "\n")
+	if a := strings.TrimLeft(after, "\n"); a != "" {
+		rest = strings.TrimRight(rest+"\n\n"+a, "\n")
+	}
+	rest = strings.TrimLeft(rest, "\n")
+	if cfg.dryRun {
+		cfg.printf("   [DRY] Would remove the bmad2vibe section of %s\n", path)
+		return
+	}
+	if rest == "" {
+		err = os.Remove(path)
+	} else {
+		err = os.WriteFile(path, []byte(rest+"\n"), 0o644)
+	}
+	if err != nil {
+		report.err(diagIO, path, "update: %v", err)
+		return
+	}
+	report.removed = append(report.removed, path+" (bmad2vibe section)")
+}
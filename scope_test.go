@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMergeAgentsMD(t *testing.T) {
+	section := "## BMAD\n\n| Agent |\n"
+	block := agentsMDBegin + "\n## BMAD\n\n| Agent |\n" + agentsMDEnd + "\n"
+	tests := []struct {
+		name, existing, want string
+	}{
+		{"no file", "", block},
+		{"blank file", "\n\n", block},
+		{"no markers", "# Project\n\nBuild with make.\n", "# Project\n\nBuild with make.\n\n" + block},
+		{
+			"markers between user text",
+			"# Project\n\n" + agentsMDBegin + "\nold index\n" + agentsMDEnd + "\n\n## Conventions\n\nTabs.\n",
+			"# Project\n\n" + block + "\n## Conventions\n\nTabs.\n",
+		},
+		{"markers at the end without a newline", "Intro\n" + agentsMDBegin + "\nold\n" + agentsMDEnd, "Intro\n" + block},
+		// A begin marker without an end is not a section: append a new one.
+		{"unterminated section", "Notes\n" + agentsMDBegin + "\n", "Notes\n" + agentsMDBegin + "\n\n" + block},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeAgentsMD(tt.existing, section)
+			if got != tt.want {
+				t.Errorf("merge:\n got %q\nwant %q", got, tt.want)
+			}
+			if again := mergeAgentsMD(got, section); again != got {
+				t.Errorf("second merge changed the file:\n got %q\nwant %q", again, got)
+			}
+		})
+	}
+}
+
+func TestSharedIndexLifecycle(t *testing.T) {
+	root := t.TempDir()
+	cfg := &config{target: vibeTarget{}, scope: scopeProject, projectRoot: root, vibeHome: filepath.Join(root, ".vibe")}
+	path := filepath.Join(root, "AGENTS.md")
+	read := func() string {
+		data, _ := os.ReadFile(path)
+		return string(data)
+	}
+
+	// No AGENTS.md yet: the section is the whole file, removed with it.
+	report := &conversionReport{}
+	writeSharedIndex(cfg, "## BMAD\n", report)
+	if read() != agentsMDBegin+"\n## BMAD\n"+agentsMDEnd+"\n" || len(report.added) != 1 {
+		t.Fatalf("new file: %q, added %v", read(), report.added)
+	}
+	removeSharedIndex(cfg, &conversionReport{})
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("AGENTS.md left with only: %q", read())
+	}
+
+	// The user's text around the section survives updates and removal.
+	user := "# Project\n\nRun go test.\n"
+	os.WriteFile(path, []byte(user), 0o644)
+	writeSharedIndex(cfg, "## BMAD v1\n", &conversionReport{})
+	os.WriteFile(path, []byte(read()+"\n## Later notes\n"), 0o644)
+	writeSharedIndex(cfg, "## BMAD v2\n", &conversionReport{})
+	want := user + "\n" + agentsMDBegin + "\n## BMAD v2\n" + agentsMDEnd + "\n\n## Later notes\n"
+	if read() != want {
+		t.Fatalf("update:\n got %q\nwant %q", read(), want)
+	}
+
+	report = &conversionReport{}
+	writeSharedIndex(cfg, "## BMAD v2\n", report)
+	if read() != want || len(report.unchanged) != 1 {
+		t.Errorf("rerun: unchanged %v, file %q", report.unchanged, read())
+	}
+
+	removeSharedIndex(cfg, &conversionReport{})
+	if got := read(); got != user+"\n## Later notes\n" {
+		t.Errorf("after removal: %q", got)
+	}
+}
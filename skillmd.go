@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// --- SKILL.md frontmatter ---
+//
+// Skills follow the AgentSkills spec: a YAML frontmatter with a name that
+// matches the skill directory and a description telling the model when to
+// use the skill. Vibe adds user-invocable.
+
+// skillKeys are the frontmatter keys of a SKILL.md.
+var skillKeys = map[string]bool{
+	"name": true, "description": true, "license": true, "compatibility": true,
+	"metadata": true, "allowed-tools": true, "user-invocable": true,
+}
+
+const (
+	maxSkillName        = 64
+	maxSkillDescription = 1024
+)
+
+// skillNamePattern is lowercase letters and digits in hyphen-separated words.
+var skillNamePattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// checkSkillMD validates the frontmatter of the SKILL.md rel (relative to
+// the install dir) of the skill directory dir; tools are the valid
+// allowed-tools.
+func checkSkillMD(rel, dir, content string, tools map[string]bool, report *conversionReport) {
+	front, _, ok := splitFrontmatter(content)
+	if !ok {
+		report.err(diagInvalidFrontmatter, rel, "no YAML frontmatter (--- block at the top of the file)")
+		return
+	}
+	// The frontmatter starts on the second line of the file.
+	line := func(key string) int {
+		if n := yamlKeyLine(front, key); n > 0 {
+			return n + 1
+		}
+		return 0
+	}
+
+	parsed, err := parseYAML(front)
+	if err != nil {
+		reportYAMLError(rel, err, report)
+		return
+	}
+	fm := yamlMap(parsed)
+	if fm == nil {
+		report.err(diagInvalidFrontmatter, rel, "frontmatter is not a YAML mapping")
+		return
+	}
+	for _, k := range sortedKeys(fm) {
+		if !skillKeys[k] {
+			report.warnAt(diagUnknownKey, rel, line(k), "unknown frontmatter key %q", k)
+		}
+	}
+
+	name, isStr := fm["name"].(string)
+	switch {
+	case fm["name"] == nil:
+		report.err(diagInvalidSkillName, rel, "missing name")
+	case !isStr:
+		report.errAt(diagInvalidSkillName, rel, line("name"), "name: want a string")
+	case name != dir:
+		report.errAt(diagInvalidSkillName, rel, line("name"), "name %q does not match the skill directory %q", name, dir)
+	case len(name) > maxSkillName:
+		report.errAt(diagInvalidSkillName, rel, line("name"), "name is %d characters, the limit is %d", len(name), maxSkillName)
+	case !skillNamePattern.MatchString(name):
+		report.errAt(diagInvalidSkillName, rel, line("name"), "name %q: use lowercase letters, digits and single hyphens", name)
+	}
+
+	desc, isStr := fm["description"].(string)
+	raw := yamlRawValue(front, "description")
+	switch {
+	case fm["description"] == nil:
+		report.err(diagInvalidSkillDescription, rel, "missing description")
+	case !isStr:
+		report.errAt(diagInvalidSkillDescription, rel, line("description"), "description: want a string (quote it)")
+	case strings.TrimSpace(desc) == "":
+		report.errAt(diagInvalidSkillDescription, rel, line("description"), "empty description")
+	case utf8.RuneCountInString(desc) > maxSkillDescription:
+		report.errAt(diagInvalidSkillDescription, rel, line("description"), "description is %d characters, the limit is %d", utf8.RuneCountInString(desc), maxSkillDescription)
+	case needsYAMLQuotes(raw):
+		report.errAt(diagInvalidSkillDescription, rel, line("description"), "description must be quoted: plain YAML scalars cannot contain \": \" or \" #\"")
+	}
+
+	if allowed, ok := fm["allowed-tools"]; ok {
+		var names []string
+		switch t := allowed.(type) {
+		case string: // the spec's space-delimited form
+			names = strings.Fields(t)
+		case []any:
+			names = yamlStrings(t)
+			if len(names) != len(t) {
+				report.errAt(diagInvalidField, rel, line("allowed-tools"), "allowed-tools: want a list of tool names")
+			}
+		default:
+			report.errAt(diagInvalidField, rel, line("allowed-tools"), "allowed-tools: want a list of tool names")
+		}
+		for _, n := range names {
+			if !tools[n] {
+				report.errAt(diagUnknownTool, rel, line("allowed-tools"), "allowed-tools: unknown tool %q", n)
+			}
+		}
+	}
+}
+
+// reportYAMLError reports the frontmatter parse error err of rel, on its line
+// of the file when known.
+func reportYAMLError(rel string, err error, report *conversionReport) {
+	line, msg := 0, err.Error()
+	var ye *yamlError
+	if errors.As(err, &ye) {
+		line, msg = ye.Line+1, ye.Msg
+	}
+	report.errAt(diagInvalidFrontmatter, rel, line, "%s", msg)
+}
+
+// yamlKeyLine returns the line (1-based) of the top-level key in src, or 0.
+func yamlKeyLine(src, key string) int {
+	if key == "" {
+		return 0
+	}
+	for i, l := range strings.Split(src, "\n") {
+		if rest, ok := strings.CutPrefix(l, key); ok && strings.HasPrefix(strings.TrimLeft(rest, " "), ":") {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// yamlRawValue returns the unparsed inline value of a top-level key.
+func yamlRawValue(src, key string) string {
+	n := yamlKeyLine(src, key)
+	if n == 0 {
+		return ""
+	}
+	l := strings.Split(src, "\n")[n-1]
+	_, v, _ := strings.Cut(l, ":")
+	return strings.TrimSpace(v)
+}
+
+// needsYAMLQuotes reports whether a plain (unquoted) scalar is ambiguous:
+// other YAML parsers would read it as a mapping or cut it at a comment.
+func needsYAMLQuotes(raw string) bool {
+	if raw == "" || strings.ContainsAny(raw[:1], `"'|>`) {
+		return false
+	}
+	return strings.Contains(raw, ": ") || strings.Contains(raw, " #") || strings.ContainsAny(raw[:1], "[]{}&*!%@`,")
+}
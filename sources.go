@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// --- Source resolution ---
+
+const (
+	bmadBundlesRepo = "https://github.com/bmad-code-org/bmad-bundles.git"
+	bmadMethodRepo  = "https://github.com/bmad-code-org/BMAD-METHOD.git"
+)
+
+// sourceRepo is one of the two BMAD repositories the converter reads.
+type sourceRepo struct {
+	Name string // "bmad-bundles" or "BMAD-METHOD"
+	Flag string // flag prefix: "bundles" or "method"
+	URL  string
+	Ref  string // tag, branch or commit to check out; empty for the default branch
+	Dir  string // local checkout
+	SHA  string // commit checked out in Dir, empty if Dir is not a git work tree
+
+	Local bool // Dir was given with -<flag>-dir; URL and Ref do not apply
+}
+
+// label identifies the source in generated headers, e.g. "BMAD-METHOD@1a2b3c4d5e6f".
+func (r *sourceRepo) label() string {
+	if r.SHA == "" {
+		return r.Name
+	}
+	return r.Name + "@" + shortSHA(r.SHA)
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
+
+// resolveSources points both repos at a local checkout: the directory given
+// on the command line, a checkout in the source cache, or (with -no-cache) a
+// fresh clone in the temp dir.
+func resolveSources(cfg *config, bundlesFlag, methodFlag string) error {
+	if err := resolveSource(cfg, &cfg.bundles, bundlesFlag); err != nil {
+		return err
+	}
+	return resolveSource(cfg, &cfg.method, methodFlag)
+}
+
+func resolveSource(cfg *config, repo *sourceRepo, localDir string) error {
+	if localDir != "" {
+		if repo.Ref != "" {
+			return fmt.Errorf("-%s-ref cannot be used with -%s-dir; check out the ref in %s instead", repo.Flag, repo.Flag, localDir)
+		}
+		repo.Dir, repo.Local = localDir, true
+		repo.SHA = gitHead(localDir)
+		cfg.printf("   📂 Using local %s: %s\n", repo.Flag, localDir)
+		return nil
+	}
+
+	if cfg.cacheDir != "" {
+		return resolveCached(cfg, repo)
+	}
+
+	repo.Dir = filepath.Join(cfg.tmpDir, repo.Name)
+	if err := cloneRepo(cfg, repo.URL, repo.Dir, repo.Ref); err != nil {
+		return fmt.Errorf("failed to clone %s: %v", repo.Name, err)
+	}
+	repo.SHA = gitHead(repo.Dir)
+	cfg.printf("   📌 %s @ %s\n", repo.Name, shortSHA(repo.SHA))
+	return nil
+}
+
+// cloneRepo clones url into dest at ref. Branches, tags and full commit SHAs
+// are fetched shallowly; abbreviated SHAs, and servers that refuse to serve a
+// commit by SHA, fall back to fetching the full history.
+func cloneRepo(cfg *config, url, dest, ref string) error {
+	out := cfg.gitOutput()
+	if ref == "" {
+		cfg.printf("   📥 Cloning %s...\n", url)
+		return runGit(out, "", "clone", "--depth", "1", url, dest)
+	}
+
+	cfg.printf("   📥 Cloning %s @ %s...\n", url, ref)
+	if err := runGit(out, "", "init", "-q", dest); err != nil {
+		return err
+	}
+	if err := runGit(out, dest, "remote", "add", "origin", url); err != nil {
+		return err
+	}
+	if runGit(out, dest, "fetch", "--depth", "1", "origin", ref) == nil {
+		return runGit(out, dest, "checkout", "-q", "--detach", "FETCH_HEAD")
+	}
+
+	if err := runGit(out, dest, "fetch", "--tags", "origin", "+refs/heads/*:refs/remotes/origin/*"); err != nil {
+		return fmt.Errorf("fetch %s: %v", url, err)
+	}
+	sha, err := resolveRef(dest, ref)
+	if err != nil {
+		return err
+	}
+	return runGit(out, dest, "checkout", "-q", "--detach", sha)
+}
+
+// resolveRef resolves ref (tag, commit, or branch of origin) to a commit SHA
+// in the repository at dir.
+func resolveRef(dir, ref string) (string, error) {
+	for _, candidate := range []string{ref, "origin/" + ref} {
+		out, err := exec.Command("git", "-C", dir, "rev-parse", "--verify", "--quiet", candidate+"^{commit}").Output()
+		if err == nil {
+			return strings.TrimSpace(string(out)), nil
+		}
+	}
+	return "", fmt.Errorf("ref %q does not exist (not a branch, tag or commit)", ref)
+}
+
+// runGit runs git in dir (the current directory if empty), streaming its
+// output to out; a nil out discards it.
+func runGit(out io.Writer, dir string, args ...string) error {
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+	cmd := exec.Command("git", args...)
+	if out != nil {
+		cmd.Stdout = out
+		cmd.Stderr = os.Stderr
+	} else {
+		cmd.Stdout = io.Discard
+		cmd.Stderr = io.Discard
+	}
+	return cmd.Run()
+}
+
+// gitHead returns the commit checked out in dir, or "" if dir is not a git
+// work tree.
+func gitHead(dir string) string {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// --- Targets ---
+//
+// A target is an agent tool bmad2vibe installs BMAD for. Workflows, tasks
+// and data become AgentSkills for every target; the target decides how
+// agents are laid out, what its tools are called, how the install is
+// indexed, and how agent files are validated. Tool names are Vibe names
+// throughout the conversion and the config file; targets map them.
+
+type target interface {
+	name() string    // -target value
+	product() string // e.g. "Mistral Vibe"
+	short() string   // e.g. "Vibe", in running text
+	dir() string     // default install directory name, e.g. ".vibe"
+
+	// tool maps a Vibe tool name to the target's; tools lists valid names.
+	tool(name string) string
+	tools() map[string]bool
+
+	// agentFiles renders an agent as files relative to the install dir.
+	agentFiles(a agentSpec) []namedContent
+	agentPath(slug string) string  // main agent file, relative to the install dir
+	promptPath(slug string) string // file holding the system prompt
+	prompts(home string) []string  // every generated system prompt on disk
+	listAgents(home string) []agentEntry
+	// validateAgents checks the agent files on disk and returns the number
+	// of agents and prompts.
+	validateAgents(cfg *config, report *conversionReport) (agents, prompts int)
+
+	// runtime returns the sentence introducing the runtime and the rows of
+	// the runtime adaptation table specific to the target.
+	runtime() (intro string, rows []string)
+	index() string                 // index file name, e.g. AGENTS.md
+	sharedIndex(scope string) bool // the index is a section of a file the user owns
+	globalIndexOptIn() bool        // the global index applies to every project, so -global-index is needed
+	launch(slug string) string     // command starting an agent
+	launchHelp() string
+}
+
+var targets = map[string]target{
+	"vibe":   vibeTarget{},
+	"claude": claudeTarget{},
+}
+
+// parseTargets resolves a comma-separated -target value.
+func parseTargets(s string) ([]target, error) {
+	var out []target
+	for _, n := range unique(splitTrim(s, ",")) {
+		t, ok := targets[n]
+		if !ok {
+			return nil, fmt.Errorf("unknown target %q (want %s)", n, strings.Join(sortedKeys(targets), " or "))
+		}
+		out = append(out, t)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no target")
+	}
+	return out, nil
+}
+
+// agentSpec is a generated agent, before it is rendered for a target.
+type agentSpec struct {
+	Slug        string
+	Comments    []string // provenance, one line each
+	DisplayName string
+	Description string
+	Policy      policy
+	Prompt      string
+}
+
+// agentEntry is an installed agent, as listed in the index.
+type agentEntry struct {
+	Slug        string
+	Name        string
+	Description string
+	Shortcut    bool // workflow shortcut agent
+}
+
+// shortcutMarker identifies workflow shortcut agents in their provenance.
+const shortcutMarker = "workflow shortcut"
+
+// generatedAgent reports whether line is the first provenance line of a
+// generated agent: "Auto-generated by bmad2vibe", or "Auto-generated workflow
+// shortcut agent by bmad2vibe". User agents lack it and are left out of the
+// index.
+func generatedAgent(line string) bool {
+	line = strings.TrimSpace(line)
+	return line == "Auto-generated by bmad2vibe" || line == "Auto-generated "+shortcutMarker+" agent by bmad2vibe"
+}
+
+// mapTools maps Vibe tool names for t.
+func mapTools(t target, names []string) []string {
+	out := make([]string, len(names))
+	for i, n := range names {
+		out[i] = t.tool(n)
+	}
+	return out
+}
+
+// --- Vibe target ---
+
+// vibeTarget installs into a Vibe home: agents/<slug>.toml referencing
+// prompts/<slug>.md, and skills/.
+type vibeTarget struct{}
+
+func (vibeTarget) name() string    { return "vibe" }
+func (vibeTarget) product() string { return "Mistral Vibe" }
+func (vibeTarget) short() string   { return "Vibe" }
+func (vibeTarget) dir() string     { return ".vibe" }
+
+func (vibeTarget) tool(name string) string { return name }
+func (vibeTarget) tools() map[string]bool  { return vibeTools }
+
+func (t vibeTarget) agentFiles(a agentSpec) []namedContent {
+	toml := vibeAgent{
+		Comments:       a.Comments,
+		DisplayName:    a.DisplayName,
+		Description:    a.Description,
+		Safety:         a.Policy.Safety,
+		AutoApprove:    a.Policy.AutoApprove,
+		SystemPromptID: a.Slug,
+		EnabledTools:   a.Policy.Tools,
+	}.toml()
+	return []namedContent{
+		{name: t.agentPath(a.Slug), content: toml},
+		{name: t.promptPath(a.Slug), content: a.Prompt},
+	}
+}
+
+func (vibeTarget) agentPath(slug string) string  { return "agents/" + slug + ".toml" }
+func (vibeTarget) promptPath(slug string) string { return "prompts/" + slug + ".md" }
+
+func (vibeTarget) prompts(home string) []string {
+	files, _ := filepath.Glob(filepath.Join(home, "prompts", "bmad-*.md"))
+	return files
+}
+
+func (vibeTarget) listAgents(home string) []agentEntry {
+	files, _ := filepath.Glob(filepath.Join(home, "agents", "bmad-*.toml"))
+	var out []agentEntry
+	for _, f := range files {
+		data, _ := os.ReadFile(f)
+		first, _, _ := strings.Cut(string(data), "\n")
+		if c, ok := strings.CutPrefix(first, "#"); !ok || !generatedAgent(c) {
+			continue
+		}
+		doc, _ := parseTOML(string(data))
+		e := agentEntry{Slug: strings.TrimSuffix(filepath.Base(f), ".toml"), Shortcut: strings.Contains(string(data), shortcutMarker)}
+		e.Name, _ = doc["display_name"].(string)
+		e.Description, _ = doc["description"].(string)
+		out = append(out, e)
+	}
+	return out
+}
+
+var shortcutSkillRef = regexp.MustCompile("Skill slug: `([^`]+)`")
+
+func (vibeTarget) validateAgents(cfg *config, report *conversionReport) (int, int) {
+	agentsDir := filepath.Join(cfg.vibeHome, "agents")
+	promptsDir := filepath.Join(cfg.vibeHome, "prompts")
+	skillsDir := filepath.Join(cfg.vibeHome, "skills")
+
+	tomlFiles, _ := filepath.Glob(filepath.Join(agentsDir, "bmad-*.toml"))
+	promptFiles, _ := filepath.Glob(filepath.Join(promptsDir, "bmad-*.md"))
+
+	// 1. TOML syntax, fields and types + prompt cross-ref
+	agents := make(map[string]map[string]any) // parsed agent files by path
+	for _, tp := range tomlFiles {
+		data, _ := os.ReadFile(tp)
+		rel := "agents/" + filepath.Base(tp)
+		doc := checkAgentTOML(rel, string(data), report)
+		if doc == nil {
+			continue
+		}
+		agents[tp] = doc
+		if pid, _ := doc["system_prompt_id"].(string); pid != "" && !fileExists(filepath.Join(promptsDir, pid+".md")) {
+			report.err(diagMissingPrompt, rel, "prompt %s.md not found", pid)
+		}
+	}
+
+	// 2. Prompt size
+	for _, p := range promptFiles {
+		info, _ := os.Stat(p)
+		if info != nil && info.Size() < 50 {
+			report.warn(diagSmallPrompt, "prompts/"+filepath.Base(p), "suspiciously small (%d bytes)", info.Size())
+		}
+	}
+
+	// 3. Orphaned prompts
+	for _, p := range promptFiles {
+		slug := strings.TrimSuffix(filepath.Base(p), ".md")
+		if !fileExists(filepath.Join(agentsDir, slug+".toml")) {
+			report.warn(diagOrphanPrompt, "prompts/"+slug+".md", "orphaned prompt, no agents/%s.toml", slug)
+		}
+	}
+
+	// 4. Workflow shortcut → skill exists
+	for _, tp := range tomlFiles {
+		data, _ := os.ReadFile(tp)
+		if !strings.Contains(string(data), shortcutMarker) {
+			continue
+		}
+		pid, _ := agents[tp]["system_prompt_id"].(string)
+		pData, _ := os.ReadFile(filepath.Join(promptsDir, pid+".md"))
+		m := shortcutSkillRef.FindStringSubmatch(string(pData))
+		if len(m) >= 2 && !dirExists(filepath.Join(skillsDir, m[1])) {
+			report.err(diagMissingSkill, "agents/"+filepath.Base(tp), "skill %s not found", m[1])
+		}
+	}
+	return len(tomlFiles), len(promptFiles)
+}
+
+func (vibeTarget) runtime() (string, []string) {
+	return "You are running inside **Mistral Vibe** CLI, NOT Claude Code/Cursor/Windsurf.", []string{
+		"| `ask_user_question` | Vibe interactive question tool |",
+		"| `workflow.xml` engine | Follow workflow steps sequentially |",
+		"| `task` tool (subagent) | Vibe `task` tool for delegation |",
+	}
+}
+
+func (vibeTarget) index() string { return "AGENTS.md" }
+
+// A global Vibe install has its own AGENTS.md, to copy into projects.
+func (vibeTarget) sharedIndex(scope string) bool { return scope == scopeProject }
+func (vibeTarget) globalIndexOptIn() bool        { return false }
+
+func (vibeTarget) launch(slug string) string { return "vibe --agent " + slug }
+func (vibeTarget) launchHelp() string {
+	return "Launch: `vibe --agent <name>` or `Shift+Tab` in interactive mode."
+}
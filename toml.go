@@ -0,0 +1,639 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// --- TOML parser ---
+//
+// A small TOML v1.0 reader covering what bmad2vibe reads and writes: tables,
+// arrays of tables, dotted and quoted keys, the four string forms, integers,
+// floats, booleans, arrays and inline tables. Dates and times are rejected.
+// Values decode to string, int64, float64, bool, []any and map[string]any.
+//
+// It lives here rather than in a dependency because the module has none: it
+// reads bmad2vibe.toml (per-path conflict rules, safety policy, sources) and
+// checks the agent files bmad2vibe writes.
+
+type tomlError struct {
+	Line int
+	Msg  string
+}
+
+func (e *tomlError) Error() string { return fmt.Sprintf("line %d: %s", e.Line, e.Msg) }
+
+type tomlParser struct {
+	src  string
+	pos  int
+	line int
+
+	root        map[string]any
+	current     map[string]any
+	currentPath string
+	// defined tracks explicitly declared tables and keys so that redefinitions
+	// are rejected; inline tracks values that may not be extended later.
+	defined map[string]bool
+	inline  map[string]bool
+}
+
+// parseTOML parses a TOML document into nested maps.
+func parseTOML(src string) (map[string]any, error) {
+	p := &tomlParser{
+		src:     src,
+		line:    1,
+		root:    make(map[string]any),
+		defined: make(map[string]bool),
+		inline:  make(map[string]bool),
+	}
+	p.current = p.root
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return p.root, nil
+}
+
+func (p *tomlParser) errorf(format string, a ...any) error {
+	return &tomlError{Line: p.line, Msg: fmt.Sprintf(format, a...)}
+}
+
+func (p *tomlParser) eof() bool { return p.pos >= len(p.src) }
+
+func (p *tomlParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *tomlParser) next() byte {
+	c := p.src[p.pos]
+	p.pos++
+	if c == '\n' {
+		p.line++
+	}
+	return c
+}
+
+func (p *tomlParser) skipSpace() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+func (p *tomlParser) skipComment() error {
+	if p.peek() != '#' {
+		return nil
+	}
+	for !p.eof() && p.peek() != '\n' {
+		c := p.peek()
+		if c < 0x20 && c != '\t' && c != '\r' || c == 0x7f {
+			return p.errorf("control character in comment")
+		}
+		p.pos++
+	}
+	return nil
+}
+
+// endOfLine consumes trailing whitespace, an optional comment and the newline.
+func (p *tomlParser) endOfLine() error {
+	p.skipSpace()
+	if err := p.skipComment(); err != nil {
+		return err
+	}
+	if p.eof() {
+		return nil
+	}
+	if p.peek() == '\r' {
+		p.pos++
+	}
+	if p.eof() || p.peek() != '\n' {
+		return p.errorf("expected end of line, found %q", p.peek())
+	}
+	p.next()
+	return nil
+}
+
+// skipBlank skips whitespace, comments and newlines (inside arrays).
+func (p *tomlParser) skipBlank() error {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\r', '\n':
+			p.next()
+		case '#':
+			if err := p.skipComment(); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (p *tomlParser) parse() error {
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return nil
+		}
+		switch p.peek() {
+		case '\n', '\r', '#':
+		case '[':
+			if err := p.parseTableHeader(); err != nil {
+				return err
+			}
+		default:
+			if err := p.parseKeyValue(p.current, p.currentPath); err != nil {
+				return err
+			}
+		}
+		if err := p.endOfLine(); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *tomlParser) parseTableHeader() error {
+	p.next() // [
+	array := false
+	if p.peek() == '[' {
+		p.next()
+		array = true
+	}
+	p.skipSpace()
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	p.skipSpace()
+	closing := "]"
+	if array {
+		closing = "]]"
+	}
+	if !strings.HasPrefix(p.src[p.pos:], closing) {
+		return p.errorf("expected %q after table name", closing)
+	}
+	p.pos += len(closing)
+
+	name := strings.Join(keys, ".")
+	parent, err := p.descend(p.root, keys[:len(keys)-1], "")
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+
+	if array {
+		existing, ok := parent[last]
+		var list []any
+		if ok {
+			l, isList := existing.([]any)
+			if !isList || p.inline[name] {
+				return p.errorf("cannot redefine %q as an array of tables", name)
+			}
+			list = l
+		}
+		t := make(map[string]any)
+		parent[last] = append(list, t)
+		p.current, p.currentPath = t, name
+		// Keys of the new element may be defined again.
+		for k := range p.defined {
+			if strings.HasPrefix(k, name+".") {
+				delete(p.defined, k)
+			}
+		}
+		for k := range p.inline {
+			if strings.HasPrefix(k, name+".") {
+				delete(p.inline, k)
+			}
+		}
+		return nil
+	}
+
+	if p.defined[name] {
+		return p.errorf("table %q defined more than once", name)
+	}
+	p.defined[name] = true
+	switch existing := parent[last].(type) {
+	case nil:
+		t := make(map[string]any)
+		parent[last] = t
+		p.current, p.currentPath = t, name
+	case map[string]any:
+		if p.inline[name] {
+			return p.errorf("cannot extend inline table %q", name)
+		}
+		p.current, p.currentPath = existing, name
+	default:
+		return p.errorf("key %q is already defined as a value", name)
+	}
+	return nil
+}
+
+// descend walks (and creates) the intermediate tables named by keys. prefix is
+// the dotted path of table within the document, used for redefinition checks.
+func (p *tomlParser) descend(table map[string]any, keys []string, prefix string) (map[string]any, error) {
+	path := prefix
+	for _, k := range keys {
+		if path != "" {
+			path += "."
+		}
+		path += k
+		switch v := table[k].(type) {
+		case nil:
+			t := make(map[string]any)
+			table[k] = t
+			table = t
+		case map[string]any:
+			if p.inline[path] {
+				return nil, p.errorf("cannot extend inline table %q", path)
+			}
+			table = v
+		case []any:
+			if len(v) == 0 {
+				return nil, p.errorf("key %q is not a table", path)
+			}
+			t, ok := v[len(v)-1].(map[string]any)
+			if !ok || p.inline[path] {
+				return nil, p.errorf("key %q is not a table", path)
+			}
+			table = t
+		default:
+			return nil, p.errorf("key %q is already defined as a value", path)
+		}
+	}
+	return table, nil
+}
+
+// parseKey reads a possibly dotted key.
+func (p *tomlParser) parseKey() ([]string, error) {
+	var keys []string
+	for {
+		p.skipSpace()
+		var k string
+		switch c := p.peek(); {
+		case c == '"':
+			s, err := p.parseBasicString()
+			if err != nil {
+				return nil, err
+			}
+			k = s
+		case c == '\'':
+			s, err := p.parseLiteralString()
+			if err != nil {
+				return nil, err
+			}
+			k = s
+		default:
+			start := p.pos
+			for !p.eof() && isBareKeyChar(p.peek()) {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, p.errorf("expected key, found %q", p.peek())
+			}
+			k = p.src[start:p.pos]
+		}
+		keys = append(keys, k)
+		p.skipSpace()
+		if p.peek() != '.' {
+			return keys, nil
+		}
+		p.next()
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// parseKeyValue parses "key = value" into table. prefix is the dotted path of
+// table, used to track definitions.
+func (p *tomlParser) parseKeyValue(table map[string]any, prefix string) error {
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	if p.peek() != '=' {
+		return p.errorf("expected '=' after key %q", strings.Join(keys, "."))
+	}
+	p.next()
+	p.skipSpace()
+
+	parent, err := p.descend(table, keys[:len(keys)-1], prefix)
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	full := strings.Join(keys, ".")
+	if prefix != "" {
+		full = prefix + "." + full
+	}
+	if _, exists := parent[last]; exists {
+		return p.errorf("key %q defined more than once", full)
+	}
+	v, err := p.parseValue(full)
+	if err != nil {
+		return err
+	}
+	parent[last] = v
+	p.defined[full] = true
+	// Tables created through dotted keys cannot be reopened with [header].
+	for i := 1; i < len(keys); i++ {
+		sub := strings.Join(keys[:i], ".")
+		if prefix != "" {
+			sub = prefix + "." + sub
+		}
+		p.defined[sub] = true
+	}
+	return nil
+}
+
+func (p *tomlParser) parseValue(path string) (any, error) {
+	if p.eof() {
+		return nil, p.errorf("missing value")
+	}
+	switch c := p.peek(); {
+	case strings.HasPrefix(p.src[p.pos:], `"""`):
+		return p.parseMultilineBasicString()
+	case strings.HasPrefix(p.src[p.pos:], `'''`):
+		return p.parseMultilineLiteralString()
+	case c == '"':
+		return p.parseBasicString()
+	case c == '\'':
+		return p.parseLiteralString()
+	case c == '[':
+		return p.parseArray(path)
+	case c == '{':
+		return p.parseInlineTable(path)
+	case strings.HasPrefix(p.src[p.pos:], "true"):
+		p.pos += 4
+		return true, nil
+	case strings.HasPrefix(p.src[p.pos:], "false"):
+		p.pos += 5
+		return false, nil
+	default:
+		return p.parseNumber()
+	}
+}
+
+func (p *tomlParser) parseBasicString() (string, error) {
+	p.next() // "
+	var b strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.next()
+		switch {
+		case c == '"':
+			return b.String(), nil
+		case c == '\\':
+			if err := p.parseEscape(&b); err != nil {
+				return "", err
+			}
+		case c < 0x20 && c != '\t' || c == 0x7f:
+			return "", p.errorf("control character %U in string", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+func (p *tomlParser) parseEscape(b *strings.Builder) error {
+	if p.eof() {
+		return p.errorf("unterminated escape")
+	}
+	switch c := p.next(); c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case '"':
+		b.WriteByte('"')
+	case '\\':
+		b.WriteByte('\\')
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if p.pos+n > len(p.src) {
+			return p.errorf("short unicode escape")
+		}
+		code, err := strconv.ParseUint(p.src[p.pos:p.pos+n], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return p.errorf("invalid unicode escape \\%c%s", c, p.src[p.pos:p.pos+n])
+		}
+		p.pos += n
+		b.WriteRune(rune(code))
+	default:
+		return p.errorf("invalid escape \\%c", c)
+	}
+	return nil
+}
+
+func (p *tomlParser) parseLiteralString() (string, error) {
+	p.next() // '
+	start := p.pos
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", p.errorf("unterminated literal string")
+		}
+		if p.next() == '\'' {
+			return p.src[start : p.pos-1], nil
+		}
+	}
+}
+
+func (p *tomlParser) parseMultilineBasicString() (string, error) {
+	p.pos += 3
+	p.trimLeadingNewline()
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated multiline string")
+		}
+		if strings.HasPrefix(p.src[p.pos:], `"""`) {
+			// Up to two quotes may directly precede the closing delimiter.
+			p.pos += 3
+			for i := 0; i < 2 && p.peek() == '"'; i++ {
+				b.WriteByte('"')
+				p.pos++
+			}
+			return b.String(), nil
+		}
+		c := p.next()
+		if c != '\\' {
+			if c < 0x20 && c != '\t' && c != '\n' && c != '\r' || c == 0x7f {
+				return "", p.errorf("control character %U in string", c)
+			}
+			b.WriteByte(c)
+			continue
+		}
+		// Line-ending backslash trims the newline and following whitespace.
+		rest := p.pos
+		for rest < len(p.src) && (p.src[rest] == ' ' || p.src[rest] == '\t') {
+			rest++
+		}
+		if rest < len(p.src) && (p.src[rest] == '\n' || p.src[rest] == '\r') {
+			p.pos = rest
+			for !p.eof() && strings.ContainsRune(" \t\r\n", rune(p.peek())) {
+				p.next()
+			}
+			continue
+		}
+		if err := p.parseEscape(&b); err != nil {
+			return "", err
+		}
+	}
+}
+
+func (p *tomlParser) parseMultilineLiteralString() (string, error) {
+	p.pos += 3
+	p.trimLeadingNewline()
+	start := p.pos
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated multiline literal string")
+		}
+		if strings.HasPrefix(p.src[p.pos:], `'''`) {
+			end := p.pos
+			p.pos += 3
+			for i := 0; i < 2 && p.peek() == '\''; i++ {
+				p.pos++
+				end++
+			}
+			return p.src[start:end], nil
+		}
+		p.next()
+	}
+}
+
+func (p *tomlParser) trimLeadingNewline() {
+	if strings.HasPrefix(p.src[p.pos:], "\r\n") {
+		p.pos++
+	}
+	if p.peek() == '\n' {
+		p.next()
+	}
+}
+
+func (p *tomlParser) parseArray(path string) ([]any, error) {
+	p.next() // [
+	list := []any{}
+	// A static array cannot be extended by [[header]] or [header.sub].
+	p.inline[path] = true
+	for {
+		if err := p.skipBlank(); err != nil {
+			return nil, err
+		}
+		if p.eof() {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.peek() == ']' {
+			p.next()
+			return list, nil
+		}
+		v, err := p.parseValue(fmt.Sprintf("%s[%d]", path, len(list)))
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+		if err := p.skipBlank(); err != nil {
+			return nil, err
+		}
+		switch p.peek() {
+		case ',':
+			p.next()
+		case ']':
+		default:
+			return nil, p.errorf("expected ',' or ']' in array")
+		}
+	}
+}
+
+func (p *tomlParser) parseInlineTable(path string) (map[string]any, error) {
+	p.next() // {
+	t := make(map[string]any)
+	p.inline[path] = true
+	p.skipSpace()
+	if p.peek() == '}' {
+		p.next()
+		return t, nil
+	}
+	for {
+		if err := p.parseKeyValue(t, path); err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.next()
+		case '}':
+			p.next()
+			return t, nil
+		default:
+			return nil, p.errorf("expected ',' or '}' in inline table")
+		}
+	}
+}
+
+func (p *tomlParser) parseNumber() (any, error) {
+	start := p.pos
+	for !p.eof() && strings.IndexByte("+-0123456789abcdefABCDEFxobinf._:TZ", p.peek()) >= 0 {
+		p.pos++
+	}
+	tok := p.src[start:p.pos]
+	if tok == "" {
+		return nil, p.errorf("invalid value starting with %q", p.peek())
+	}
+	switch tok {
+	case "inf", "+inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan", "+nan", "-nan":
+		return math.NaN(), nil
+	}
+	if strings.ContainsAny(tok, ":TZ") || strings.Count(tok, "-") > 1 && !strings.ContainsAny(tok, "eE") {
+		return nil, p.errorf("dates and times are not supported (%s)", tok)
+	}
+	if strings.Contains(tok, "__") || strings.HasPrefix(tok, "_") || strings.HasSuffix(tok, "_") {
+		return nil, p.errorf("invalid number %q", tok)
+	}
+	clean := strings.ReplaceAll(tok, "_", "")
+	if strings.HasPrefix(clean, "0x") || strings.HasPrefix(clean, "0o") || strings.HasPrefix(clean, "0b") {
+		base := map[byte]int{'x': 16, 'o': 8, 'b': 2}[clean[1]]
+		n, err := strconv.ParseInt(clean[2:], base, 64)
+		if err != nil {
+			return nil, p.errorf("invalid integer %q", tok)
+		}
+		return n, nil
+	}
+	digits := strings.TrimLeft(clean, "+-")
+	if len(digits) > 1 && digits[0] == '0' && digits[1] != '.' && digits[1] != 'e' && digits[1] != 'E' {
+		return nil, p.errorf("leading zeros are not allowed (%s)", tok)
+	}
+	if strings.ContainsAny(clean, ".eE") {
+		f, err := strconv.ParseFloat(clean, 64)
+		if err != nil || strings.HasPrefix(digits, ".") || strings.HasSuffix(digits, ".") || strings.Contains(clean, ".e") || strings.Contains(clean, ".E") {
+			return nil, p.errorf("invalid float %q", tok)
+		}
+		return f, nil
+	}
+	n, err := strconv.ParseInt(clean, 10, 64)
+	if err != nil {
+		return nil, p.errorf("invalid value %q", tok)
+	}
+	return n, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want map[string]any
+	}{
+		{"scalars", `s = "a\tb"
+l = 'C:\raw'
+i = -42
+f = 1.5
+b = true
+`, map[string]any{"s": "a\tb", "l": `C:\raw`, "i": int64(-42), "f": 1.5, "b": true}},
+		{"multiline strings", "a = \"\"\"\nline 1\nline 2\\\n   joined\"\"\"\nb = '''\nraw \\n'''\n",
+			map[string]any{"a": "line 1\nline 2joined", "b": "raw \\n"}},
+		{"unicode escapes", `s = "\u00e9\U0001F600"`, map[string]any{"s": "é😀"}},
+		{"tables and dotted keys", `[safety]
+default = "safe"
+agents.pm = "neutral"
+
+[safety."quoted key"]
+x = 1
+`, map[string]any{"safety": map[string]any{
+			"default":    "safe",
+			"agents":     map[string]any{"pm": "neutral"},
+			"quoted key": map[string]any{"x": int64(1)},
+		}}},
+		{"arrays of tables", `[[conflict]]
+path = "prompts/**"
+strategy = "new"
+
+[[conflict]]
+path = "agents/*.toml"
+strategy = "skip"
+`, map[string]any{"conflict": []any{
+			map[string]any{"path": "prompts/**", "strategy": "new"},
+			map[string]any{"path": "agents/*.toml", "strategy": "skip"},
+		}}},
+		{"arrays and inline tables", `tools = [
+  "read_file", # comment
+  "grep",
+]
+t = {a = 1, b = [true]}
+`, map[string]any{"tools": []any{"read_file", "grep"}, "t": map[string]any{"a": int64(1), "b": []any{true}}}},
+		{"key reused in each array element", `[[a]]
+x = [1]
+[[a]]
+[a.x]
+y = 2
+`, map[string]any{"a": []any{
+			map[string]any{"x": []any{int64(1)}},
+			map[string]any{"x": map[string]any{"y": int64(2)}},
+		}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTOML(tt.src)
+			if err != nil {
+				t.Fatalf("parseTOML: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTOML =\n%#v\nwant\n%#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		line int
+		msg  string
+	}{
+		{"unterminated string", "a = 1\nb = \"open\n", 2, "unterminated string"},
+		{"escape e is TOML 1.1", `s = "\e[0m"`, 1, `invalid escape \e`},
+		{"unknown escape", `s = "\q"`, 1, `invalid escape \q`},
+		{"duplicate key", "a = 1\na = 2\n", 2, "defined more than once"},
+		{"duplicate table", "[t]\n[t]\n", 2, "defined more than once"},
+		{"extend inline table", "t = {a = 1}\n[t.b]\n", 2, "inline table"},
+		{"extend static array with table", "x = [{a = 1}]\n[x.b]\n", 2, "not a table"},
+		{"extend static array with array of tables", "x = [{a = 1}]\n[[x]]\n", 2, "array of tables"},
+		{"table over value", "a = 1\n[a.b]\n", 2, "already defined as a value"},
+		{"control character", "s = \"a\x01b\"\n", 1, "control character"},
+		{"missing equals", "a 1\n", 1, "expected '='"},
+		{"trailing garbage", "a = 1 2\n", 1, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseTOML(tt.src)
+			te, ok := err.(*tomlError)
+			if !ok {
+				t.Fatalf("parseTOML(%q) error = %v, want a *tomlError", tt.src, err)
+			}
+			if te.Line != tt.line || !strings.Contains(te.Msg, tt.msg) {
+				t.Errorf("error = %v, want line %d containing %q", te, tt.line, tt.msg)
+			}
+		})
+	}
+}
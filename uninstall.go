@@ -0,0 +1,247 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// --- uninstall subcommand ---
+
+// runUninstall removes the files bmad2vibe generated. The manifest drives the
+// removal and guards hand-edited files; without one, files carrying the
+// bmad2vibe "auto-generated" header are removed instead.
+func runUninstall(args []string) {
+	fs := flag.NewFlagSet("uninstall", flag.ExitOnError)
+	var (
+		vibeHome   = fs.String("vibe-home", "", "Vibe home directory (default ~/.vibe, or ./.vibe with -scope project)")
+		claudeHome = fs.String("claude-home", "", "Claude Code directory for -target claude (default ~/.claude, or ./.claude with -scope project)")
+		targetName = fs.String("target", "vibe", "Tool to uninstall from: vibe or claude")
+		scope      = fs.String("scope", scopeGlobal, "Install scope: global or project")
+		modules    = fs.String("modules", "", "Comma-separated modules to remove (default: all)")
+		dryRun     = fs.Bool("dry-run", false, "Show what would be removed without deleting files")
+		verbose    = fs.Bool("verbose", false, "Verbose output")
+	)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: bmad2vibe uninstall [flags]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	t, ok := targets[*targetName]
+	if !ok {
+		log.Fatalf("invalid -target %q (want %s)", *targetName, strings.Join(sortedKeys(targets), " or "))
+	}
+	homes := map[string]string{"vibe": *vibeHome, "claude": *claudeHome}
+	home, projectRoot, err := resolveScope(*scope, homes[t.name()], t.dir())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cfg := &config{
+		target:      t,
+		vibeHome:    home,
+		scope:       *scope,
+		projectRoot: projectRoot,
+		modules:     splitTrim(*modules, ","),
+		dryRun:      *dryRun,
+		verbose:     *verbose,
+		file:        &fileConfig{},
+	}
+	cfg.paths = newVibePaths(cfg.vibeHome, cfg.scope, cfg.projectRoot)
+	report := &conversionReport{}
+
+	fmt.Println("🗑️  bmad2vibe uninstall")
+	fmt.Printf("   Target: %s\n", cfg.vibeHome)
+	if cfg.dryRun {
+		fmt.Println("   ⚠️  DRY RUN — no files will be removed")
+	}
+
+	m, err := loadManifest(cfg.vibeHome)
+	if err != nil {
+		log.Fatalf("cannot read manifest: %v", err)
+	}
+	if len(m.Files) == 0 {
+		fmt.Printf("   (no %s — falling back to generated-file headers)\n", manifestName)
+		m = scanGeneratedFiles(cfg.vibeHome)
+	}
+
+	selected := func(e manifestEntry) bool {
+		if len(cfg.modules) == 0 {
+			return true
+		}
+		for _, mod := range cfg.modules {
+			if e.Module == mod {
+				return true
+			}
+		}
+		return false
+	}
+
+	var kept []string
+	for _, rel := range m.paths() {
+		e := m.Files[rel]
+		if !selected(e) {
+			continue
+		}
+		path := filepath.Join(cfg.vibeHome, filepath.FromSlash(rel))
+		switch fileHash(path) {
+		case "":
+			delete(m.Files, rel)
+			continue
+		case e.Hash:
+		default:
+			kept = append(kept, rel)
+			continue
+		}
+		if cfg.dryRun {
+			fmt.Printf("   [DRY] Would remove %s\n", path)
+		} else {
+			if err := os.Remove(path); err != nil {
+				report.err(diagIO, rel, "remove: %v", err)
+				continue
+			}
+			removeEmptyParents(filepath.Dir(path), cfg.vibeHome)
+			cfg.removeBase(rel)
+			if cfg.verbose {
+				fmt.Printf("   - %s\n", rel)
+			}
+		}
+		delete(m.Files, rel)
+		report.removed = append(report.removed, rel)
+	}
+
+	if cfg.sharedIndex() && len(cfg.modules) == 0 {
+		removeSharedIndex(cfg, report)
+	}
+	if !cfg.dryRun {
+		// The index lists the agents dir, so refresh it after a partial removal.
+		if _, ok := m.Files[t.index()]; (ok || cfg.sharedIndex() && cfg.hasIndexSection()) && len(cfg.modules) > 0 {
+			cfg.prevManifest, cfg.manifest = m, m
+			cfg.globalIndex = true // the section exists, so it was asked for
+			generateAgentsMD(cfg, report)
+		}
+		if len(m.Files) == 0 {
+			os.Remove(filepath.Join(cfg.vibeHome, manifestName))
+			os.RemoveAll(filepath.Join(cfg.vibeHome, filepath.Dir(filepath.FromSlash(baseDir))))
+		} else if err := m.save(cfg.vibeHome); err != nil {
+			report.err(diagIO, manifestName, "write: %v", err)
+		}
+	}
+
+	fmt.Printf("\n🗑️  Removed: %d\n", len(report.removed))
+	if len(kept) > 0 {
+		fmt.Printf("\n⚠️  Kept %d file(s) edited since generation:\n", len(kept))
+		for _, k := range kept {
+			fmt.Printf("   ⚠️  %s\n", k)
+		}
+	}
+	if len(report.errors) > 0 {
+		fmt.Printf("\n❌ Errors: %d\n", len(report.errors))
+		for _, e := range report.errors {
+			fmt.Printf("   ❌ %s\n", e)
+		}
+		os.Exit(1)
+	}
+}
+
+// scanGeneratedFiles builds a manifest from the bmad2vibe headers of agents,
+// prompts and skills, for installs that predate the manifest. A generated
+// SKILL.md brings the rest of its skill directory (split steps, workflow
+// files), and bmad-<module>-data and -docs directories are taken whole. Each
+// file is hashed as found, so it is treated as unedited.
+func scanGeneratedFiles(vibeHome string) *manifest {
+	m := newManifest()
+	add := func(path string, data []byte) {
+		rel, _ := filepath.Rel(vibeHome, path)
+		rel = filepath.ToSlash(rel)
+		m.Files[rel] = manifestEntry{Hash: hashContent(data), Module: moduleFromSlug(rel)}
+	}
+
+	var candidates []string
+	for _, pattern := range []string{
+		filepath.Join("agents", "bmad-*.toml"),
+		filepath.Join("agents", "bmad-*.md"),
+		filepath.Join("prompts", "bmad-*.md"),
+	} {
+		matches, _ := filepath.Glob(filepath.Join(vibeHome, pattern))
+		candidates = append(candidates, matches...)
+	}
+	candidates = append(candidates, filepath.Join(vibeHome, "AGENTS.md"))
+	for _, path := range candidates {
+		if data, err := os.ReadFile(path); err == nil && hasGeneratedHeader(string(data)) {
+			add(path, data)
+		}
+	}
+
+	dirs, _ := filepath.Glob(filepath.Join(vibeHome, "skills", "bmad-*"))
+	for _, dir := range dirs {
+		skill, err := os.ReadFile(filepath.Join(dir, "SKILL.md"))
+		switch {
+		case err == nil && hasGeneratedHeader(string(skill)):
+		case err != nil && dataDir(dir):
+		default:
+			continue
+		}
+		filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err == nil && info.Mode().IsRegular() {
+				if data, err := os.ReadFile(path); err == nil {
+					add(path, data)
+				}
+			}
+			return nil
+		})
+	}
+	return m
+}
+
+// generatedHeaders match the provenance line bmad2vibe writes near the top
+// of prompts, skills and AGENTS.md; agents carry generatedAgent's marker.
+// Older versions wrote the same lines without the source, which is optional.
+var generatedHeaders = []*regexp.Regexp{
+	regexp.MustCompile(`^> Module: [A-Z0-9_-]+ \| Agent: \S+( \| Source: .+)? \| Generated by bmad2vibe$`),
+	regexp.MustCompile(`^> Workflow shortcut agent — auto-generated by bmad2vibe( from .+)?\.$`),
+	regexp.MustCompile(`^> Auto-generated by bmad2vibe from BMAD [A-Z0-9_-]+ module( \(.+\))?\.$`),
+	regexp.MustCompile("^> BMAD [A-Z0-9_-]+ task( \\(.+\\))?\\. `\\{project-root\\}` → cwd( \\| |\\.$)"),
+	regexp.MustCompile(`^Auto-generated by bmad2vibe(\. Copy to your project root for |; agents and skills are in )`),
+}
+
+// hasGeneratedHeader reports whether one of the first lines of content,
+// after any frontmatter, is a bmad2vibe header. Files that merely mention
+// bmad2vibe do not qualify.
+func hasGeneratedHeader(content string) bool {
+	if _, body, ok := splitFrontmatter(content); ok {
+		content = body
+	}
+	lines := strings.SplitN(strings.TrimLeft(content, "\n"), "\n", 6)
+	for _, line := range lines[:min(len(lines), 5)] {
+		line = strings.TrimRight(line, "\r")
+		if generatedAgent(strings.TrimPrefix(line, "# ")) {
+			return true
+		}
+		for _, re := range generatedHeaders {
+			if re.MatchString(line) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// moduleFromSlug extracts the module from a generated path such as
+// "skills/bmad-bmm-create-prd/SKILL.md".
+func moduleFromSlug(rel string) string {
+	parts := strings.Split(rel, "/")
+	if len(parts) < 2 {
+		return ""
+	}
+	name := strings.TrimPrefix(parts[1], "bmad-")
+	if i := strings.Index(name, "-"); i > 0 {
+		return name[:i]
+	}
+	return ""
+}
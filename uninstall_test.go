@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScanGeneratedFiles(t *testing.T) {
+	home := t.TempDir()
+	write := func(rel, content string) {
+		path := filepath.Join(home, filepath.FromSlash(rel))
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	skill := buildWorkflowSkill("bmm", "bmad-bmm-prd", "Write a PRD", "Do it.", nil, nil, nil, "BMAD-METHOD", outputFolders{}, vibeTarget{}, nil)
+
+	generated := map[string]string{
+		"agents/bmad-bmm-pm.toml":                "# Auto-generated by bmad2vibe\nactive_model = \"x\"\n",
+		"agents/bmad-bmm-prd.toml":               "# Auto-generated workflow shortcut agent by bmad2vibe\n",
+		"prompts/bmad-bmm-pm.md":                 "# PM\n\n> Module: BMM | Agent: pm | Source: bmad-bundles | Generated by bmad2vibe\n\n...",
+		"prompts/bmad-bmm-prd.md":                "# PRD\n\n> Workflow shortcut agent — auto-generated by bmad2vibe from BMAD-METHOD.\n\n...",
+		"skills/bmad-bmm-prd/SKILL.md":           skill,
+		"skills/bmad-bmm-prd/steps/step-01.md":   "# Step 1\n",
+		"skills/bmad-bmm-task-review/SKILL.md":   "---\nname: bmad-bmm-task-review\n---\n\n> BMAD BMM task (BMAD-METHOD). `{project-root}` → cwd | `{output_folder}` → `docs`.\n",
+		"skills/bmad-bmm-data/types/project.csv": "a,b\n",
+		"AGENTS.md":                              "# BMAD\n\nAuto-generated by bmad2vibe. Copy to your project root for Vibe AGENTS.md support.\n",
+
+		// Headers written before sources were recorded.
+		"prompts/bmad-bmm-dev.md":             "# Dev\n\n> Module: BMM | Agent: dev | Generated by bmad2vibe\n\n...",
+		"prompts/bmad-bmm-story.md":           "# Story\n\n> Workflow shortcut agent — auto-generated by bmad2vibe.\n\n...",
+		"skills/bmad-bmm-story/SKILL.md":      "---\nname: bmad-bmm-story\n---\n\n> Auto-generated by bmad2vibe from BMAD BMM module.\n",
+		"skills/bmad-bmm-task-index/SKILL.md": "---\nname: bmad-bmm-task-index\n---\n\n> BMAD BMM task. `{project-root}` → cwd.\n\n...",
+	}
+	// User files that talk about bmad2vibe without its headers.
+	owned := map[string]string{
+		"agents/bmad-mine.toml":           "# Generated by hand, not by bmad2vibe\n",
+		"prompts/bmad-notes.md":           "Notes on the files auto-generated by bmad2vibe.\n",
+		"skills/bmad-mine/SKILL.md":       "---\nname: bmad-mine\n---\n\nSee what bmad2vibe generated.\n",
+		"skills/bmad-mine/notes.md":       "mine\n",
+		"skills/bmad-cheat-data/SKILL.md": "---\nname: bmad-cheat-data\n---\n\nA skill, not copied data.\n",
+		"skills/other/SKILL.md":           skill,
+	}
+	for rel, content := range generated {
+		write(rel, content)
+	}
+	for rel, content := range owned {
+		write(rel, content)
+	}
+
+	m := scanGeneratedFiles(home)
+	for rel := range generated {
+		if _, ok := m.Files[rel]; !ok {
+			t.Errorf("%s not found", rel)
+		}
+	}
+	for rel := range owned {
+		if _, ok := m.Files[rel]; ok {
+			t.Errorf("%s taken as generated", rel)
+		}
+	}
+	if got := m.Files["skills/bmad-bmm-data/types/project.csv"].Module; got != "bmm" {
+		t.Errorf("data module = %q", got)
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// --- BMAD variables ---
+//
+// BMAD content refers to the module configuration with placeholders such as
+// {user_name} or {output_folder}. The BMAD installer asks for those values
+// (module.yaml) and stores them in _bmad/<module>/config.yaml; agents load
+// that file at runtime. Vibe has no such file, so the placeholders are
+// substituted at conversion time. Only placeholders that depend on where and
+// when the agent runs are left for the model.
+
+// bmadVars maps variable names to values.
+type bmadVars map[string]string
+
+// runtimePlaceholders are resolved by the model while it runs.
+var runtimePlaceholders = map[string]bool{
+	"project-root": true, "project_root": true, "installed_path": true, "config_source": true,
+	"date": true, "time": true,
+}
+
+// placeholderPattern matches {name}, but not {{name}} template fields.
+var placeholderPattern = regexp.MustCompile(`\{\{?([A-Za-z][\w-]*)\}\}?`)
+
+// moduleVars returns the variables of module, lowest precedence first:
+// default output folders, defaults from core and module module.yaml, the
+// project's _bmad/<module>/config.yaml (project installs), [variables] and
+// [output] from the config file, -var flags, then the output folder flags.
+//
+// Like the BMAD installer, defaults and overrides are answers: other answers
+// are substituted in them, then the result template of module.yaml shapes
+// them, e.g. "{project-root}/{value}". Installed config.yaml values are
+// already final.
+func (cfg *config) moduleVars(module string) bmadVars {
+	if v, ok := cfg.vars[module]; ok {
+		return v
+	}
+	answers, results := make(bmadVars), make(bmadVars)
+	for _, o := range defaultOutputs {
+		answers[o.name], results[o.name] = o.value, "{project-root}/{value}"
+	}
+	for _, mod := range unique([]string{"core", module}) {
+		readModuleYAML(filepath.Join(cfg.method.Dir, "src", mod, "module.yaml"), answers, results)
+	}
+	if cfg.projectRoot != "" {
+		for k, v := range readConfigYAML(filepath.Join(cfg.projectRoot, "_bmad", module, "config.yaml")) {
+			answers[k] = v
+			delete(results, k)
+		}
+	}
+	for _, over := range []bmadVars{cfg.file.Variables, cfg.file.Output.vars(), bmadVars(cfg.varOverrides), cfg.outputFlags.vars()} {
+		for k, v := range over {
+			answers[k] = v
+		}
+	}
+	answers.resolve()
+
+	vars := make(bmadVars, len(answers))
+	for k, v := range answers {
+		vars[k] = applyResult(results[k], v)
+	}
+	if cfg.vars == nil {
+		cfg.vars = make(map[string]bmadVars)
+	}
+	cfg.vars[module] = vars
+	return vars
+}
+
+// readModuleYAML adds the default answer and result template of each
+// variable a module.yaml asks for: a mapping with a scalar default.
+func readModuleYAML(path string, answers, results bmadVars) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	doc, err := parseYAML(string(data))
+	if err != nil {
+		return
+	}
+	for k, v := range yamlMap(doc) {
+		q := yamlMap(v)
+		if q == nil || q["default"] == nil || yamlList(q["default"]) != nil {
+			continue
+		}
+		answers[k] = yamlString(q["default"])
+		if r := yamlString(q["result"]); r != "" {
+			results[k] = r
+		} else {
+			delete(results, k)
+		}
+	}
+}
+
+// applyResult shapes an answer with a result template. Values that are
+// already rooted (absolute, ~ or {project-root}) are kept as is.
+func applyResult(result, value string) string {
+	if result == "" || filepath.IsAbs(value) || strings.HasPrefix(value, "~") || strings.HasPrefix(value, "{project-root}") {
+		return value
+	}
+	return strings.ReplaceAll(result, "{value}", value)
+}
+
+// readConfigYAML reads the scalar values of an installed config.yaml.
+func readConfigYAML(path string) bmadVars {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	doc, err := parseYAML(string(data))
+	if err != nil {
+		return nil
+	}
+	vars := make(bmadVars)
+	for k, v := range yamlMap(doc) {
+		switch v.(type) {
+		case map[string]any, []any, nil:
+		default:
+			vars[k] = yamlString(v)
+		}
+	}
+	return vars
+}
+
+// resolve expands variables used in the values of other variables, e.g.
+// planning_artifacts = "{output_folder}/planning-artifacts".
+func (v bmadVars) resolve() {
+	for range 8 { // bounded: cycles are left unexpanded
+		changed := false
+		for k, val := range v {
+			if x := v.expand(val); x != val {
+				v[k], changed = x, true
+			}
+		}
+		if !changed {
+			return
+		}
+	}
+}
+
+// expand substitutes the known variables in s.
+func (v bmadVars) expand(s string) string {
+	return placeholderPattern.ReplaceAllStringFunc(s, func(m string) string {
+		if strings.HasPrefix(m, "{{") {
+			return m
+		}
+		if val, ok := v[m[1:len(m)-1]]; ok {
+			return val
+		}
+		return m
+	})
+}
+
+// expandAll substitutes the known variables in each file.
+func (v bmadVars) expandAll(files []namedContent) []namedContent {
+	out := make([]namedContent, len(files))
+	for i, f := range files {
+		out[i] = namedContent{name: f.name, content: v.expand(f.content)}
+	}
+	return out
+}
+
+// unresolved lists the placeholders of s that are neither variables nor
+// runtime placeholders nor in declared (variables a workflow defines).
+func (v bmadVars) unresolved(s string, declared map[string]bool) []string {
+	seen := make(map[string]bool)
+	for _, m := range placeholderPattern.FindAllStringSubmatch(s, -1) {
+		name := m[1]
+		if strings.HasPrefix(m[0], "{{") || runtimePlaceholders[name] || declared[name] {
+			continue
+		}
+		if _, ok := v[name]; !ok {
+			seen["{"+name+"}"] = true
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// checkPlaceholders reports the unresolved placeholders of an output file.
+func (cfg *config) checkPlaceholders(path, module, content string, declared map[string]bool, report *conversionReport) {
+	if names := cfg.moduleVars(module).unresolved(content, declared); len(names) > 0 {
+		report.warn(diagUnresolvedPlaceholder, cfg.manifestPath(path), "unresolved placeholders: %s", strings.Join(names, ", "))
+	}
+}
+
+// activationStepPattern matches a step of an agent's activation sequence.
+var activationStepPattern = regexp.MustCompile(`(?s)(<step n="\d+">)(.*?)(</step>)`)
+
+// configException matches the rule allowing the config.yaml load at activation.
+var configException = regexp.MustCompile(`,?\s*EXCEPTION: agent activation step \d+ config\.yaml`)
+
+// preloadConfig rewrites the activation step that loads
+// _bmad/<module>/config.yaml into session variables: that file is not
+// installed, and its values are substituted at conversion time. The step
+// lists the values by name instead. Run it before expand, so the variable
+// names of the step are not replaced by their values.
+func (v bmadVars) preloadConfig(xml string) string {
+	xml = activationStepPattern.ReplaceAllStringFunc(xml, func(step string) string {
+		m := activationStepPattern.FindStringSubmatch(step)
+		body := m[2]
+		i := strings.Index(body, "session variables")
+		if i < 0 || !strings.Contains(body, "config.yaml") {
+			return step
+		}
+		var fields []string
+		for _, p := range placeholderPattern.FindAllStringSubmatch(body[i:], -1) {
+			if val, ok := v[p[1]]; ok && !strings.HasPrefix(p[0], "{{") {
+				fields = append(fields, p[1]+" = "+xmlTextEscaper.Replace(val))
+			}
+		}
+		text := "BMAD configuration is preloaded: there is no config.yaml to load."
+		if len(fields) > 0 {
+			text += " Session variables: " + strings.Join(fields, "; ") + "."
+		}
+		return m[1] + text + m[3]
+	})
+	return configException.ReplaceAllString(xml, "")
+}
+
+// --- Output folders ---
+
+// defaultOutputs are the output folder answers of the BMAD installer, used
+// when module.yaml does not ask for them (core only defines output_folder).
+var defaultOutputs = []struct{ name, value string }{
+	{"output_folder", "_bmad-output"},
+	{"planning_artifacts", "{output_folder}/planning-artifacts"},
+	{"implementation_artifacts", "{output_folder}/implementation-artifacts"},
+}
+
+// vars returns the output folders that are set, as variable answers.
+func (o outputConfig) vars() bmadVars {
+	v := make(bmadVars)
+	for name, value := range map[string]string{
+		"output_folder":            o.Folder,
+		"planning_artifacts":       o.PlanningArtifacts,
+		"implementation_artifacts": o.ImplementationArtifacts,
+	} {
+		if value != "" {
+			v[name] = value
+		}
+	}
+	return v
+}
+
+// outputFolders are the artifact folders of a module as written in prompts.
+type outputFolders struct {
+	Root           string // {output_folder}
+	Planning       string // {planning_artifacts}
+	Implementation string // {implementation_artifacts}
+}
+
+func (cfg *config) outputs(module string) outputFolders {
+	v := cfg.moduleVars(module)
+	return outputFolders{
+		Root:           folderRef(v["output_folder"]),
+		Planning:       folderRef(v["planning_artifacts"]),
+		Implementation: folderRef(v["implementation_artifacts"]),
+	}
+}
+
+// folderRef writes a folder for prompts: relative to the project root (the
+// working directory) when it is under it, with a trailing slash.
+func folderRef(v string) string {
+	return strings.TrimRight(strings.TrimPrefix(v, "{project-root}/"), "/") + "/"
+}
+
+// varFlags collects repeated -var name=value flags.
+type varFlags map[string]string
+
+func (f varFlags) String() string {
+	var out []string
+	for _, k := range sortedKeys(f) {
+		out = append(out, k+"="+f[k])
+	}
+	return strings.Join(out, ",")
+}
+
+func (f varFlags) Set(s string) error {
+	k, v, ok := strings.Cut(s, "=")
+	if !ok || strings.TrimSpace(k) == "" {
+		return fmt.Errorf("want name=value, got %q", s)
+	}
+	f[strings.TrimSpace(k)] = v
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPreloadConfig(t *testing.T) {
+	vars := bmadVars{
+		"user_name":              "Ada & co",
+		"communication_language": "French",
+		"output_folder":          "{project-root}/docs",
+	}
+	// Activation as compiled in bmad-bundles.
+	xml := `<activation critical="MANDATORY">
+      <step n="1">Load persona from this current agent file (already in context)</step>
+      <step n="2">🚨 IMMEDIATE ACTION REQUIRED - BEFORE ANY OUTPUT:
+          - Load and read {project-root}/_bmad/bmm/config.yaml NOW
+          - Store ALL fields as session variables: {user_name}, {communication_language}, {output_folder}
+          - VERIFY: If config not loaded, STOP and report error to user
+          - DO NOT PROCEED to step 3 until config is successfully loaded and variables stored
+      </step>
+      <step n="3">Remember: user's name is {user_name}</step>
+      <rules>
+        <r>ALWAYS communicate in {communication_language}</r>
+        <r>Load files ONLY when executing a user chosen workflow or a command requires it, EXCEPTION: agent activation step 2 config.yaml</r>
+      </rules>
+</activation>`
+
+	got := vars.expand(vars.preloadConfig(xml))
+	want := `<step n="2">BMAD configuration is preloaded: there is no config.yaml to load. Session variables: user_name = Ada &amp; co; communication_language = French; output_folder = {project-root}/docs.</step>`
+	if !strings.Contains(got, want) {
+		t.Errorf("config step not rewritten:\n%s", got)
+	}
+	for _, gone := range []string{"config.yaml NOW", "STOP and report", "EXCEPTION"} {
+		if strings.Contains(got, gone) {
+			t.Errorf("%q left in:\n%s", gone, got)
+		}
+	}
+	for _, kept := range []string{
+		`<step n="1">Load persona`,
+		`<step n="3">Remember: user's name is Ada & co</step>`,
+		`<r>ALWAYS communicate in French</r>`,
+		`<r>Load files ONLY when executing a user chosen workflow or a command requires it</r>`,
+	} {
+		if !strings.Contains(got, kept) {
+			t.Errorf("missing %q in:\n%s", kept, got)
+		}
+	}
+}
+
+func TestModuleVarsResolve(t *testing.T) {
+	v := bmadVars{
+		"output_folder":      "docs",
+		"planning_artifacts": "{output_folder}/planning",
+		"loop":               "{loop}",
+	}
+	v.resolve()
+	if got := applyResult("{project-root}/{value}", v["planning_artifacts"]); got != "{project-root}/docs/planning" {
+		t.Errorf("planning_artifacts = %q", got)
+	}
+	if v["loop"] != "{loop}" {
+		t.Errorf("cycle expanded to %q", v["loop"])
+	}
+	if got := v.unresolved("{output_folder} {{template}} {date} {mystery} {declared}", map[string]bool{"declared": true}); strings.Join(got, ",") != "{mystery}" {
+		t.Errorf("unresolved = %v", got)
+	}
+}
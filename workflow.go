@@ -0,0 +1,436 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// --- Workflow definitions (workflow.yaml) ---
+
+// workflowDef is a parsed workflow.yaml. File references are kept as written,
+// e.g. "{installed_path}/instructions.xml".
+type workflowDef struct {
+	Name          string
+	Description   string
+	ConfigSource  string
+	InstalledPath string
+	Instructions  string
+	Template      string // empty when the workflow has no template (template: false)
+	Validation    string
+	Variables     []workflowVar // the variables: mapping, in source order
+	Config        []workflowVar // other top-level scalars, in source order
+	WebBundle     []string      // web_bundle.web_bundle_files
+	Steps         []string      // explicit step order, paths relative to the workflow
+}
+
+type workflowVar struct {
+	Key   string
+	Value string
+}
+
+// workflowKeys are the top-level keys that parseWorkflowYAML maps to fields.
+var workflowKeys = map[string]bool{
+	"name": true, "description": true, "config_source": true, "installed_path": true,
+	"instructions": true, "template": true, "validation": true, "variables": true, "web_bundle": true, "steps": true,
+}
+
+func parseWorkflowYAML(src string) (*workflowDef, error) {
+	doc, err := parseYAML(src)
+	if err != nil {
+		return nil, err
+	}
+	m := yamlMap(doc)
+	if m == nil {
+		return nil, fmt.Errorf("workflow.yaml is not a mapping")
+	}
+	def := &workflowDef{
+		Name:          yamlString(m["name"]),
+		Description:   strings.TrimSpace(yamlString(m["description"])),
+		ConfigSource:  yamlString(m["config_source"]),
+		InstalledPath: yamlString(m["installed_path"]),
+		Instructions:  yamlString(m["instructions"]),
+		Validation:    yamlString(m["validation"]),
+	}
+	if t, ok := m["template"].(string); ok {
+		def.Template = t
+	}
+	vars := yamlMap(m["variables"])
+	for _, k := range yamlKeyOrder(src, "variables") {
+		if v, ok := vars[k]; ok {
+			def.Variables = append(def.Variables, workflowVar{k, yamlString(v)})
+		}
+	}
+	for _, k := range yamlKeyOrder(src, "") {
+		if workflowKeys[k] {
+			continue
+		}
+		switch v := m[k].(type) {
+		case map[string]any, []any:
+		default:
+			def.Config = append(def.Config, workflowVar{k, yamlString(v)})
+		}
+	}
+	def.Steps = yamlStrings(m["steps"])
+	if wb := yamlMap(m["web_bundle"]); wb != nil {
+		def.WebBundle = yamlStrings(wb["web_bundle_files"])
+	}
+	return def, nil
+}
+
+// yamlKeyOrder returns the keys of the top-level mapping (parent == "") or of
+// the block mapping under a top-level parent key, in source order. It only
+// looks at indentation, which is enough for the flat mappings of workflow.yaml.
+func yamlKeyOrder(src, parent string) []string {
+	var keys []string
+	inside := parent == ""
+	indent := -1
+	for _, line := range strings.Split(src, "\n") {
+		t := strings.TrimLeft(line, " ")
+		if t == "" || t[0] == '#' {
+			continue
+		}
+		n := len(line) - len(t)
+		if parent != "" && n == 0 {
+			inside = strings.HasPrefix(t, parent+":")
+			indent = -1
+			continue
+		}
+		if !inside {
+			continue
+		}
+		if indent < 0 {
+			indent = n
+		}
+		if n != indent || isSeqItem(t) {
+			continue
+		}
+		if c := mappingColon(t); c >= 0 {
+			keys = append(keys, strings.Trim(strings.TrimSpace(t[:c]), `"'`))
+		}
+	}
+	return keys
+}
+
+// localFile maps a file reference of the workflow to a file in its source
+// directory wfDir, if it points there: "{installed_path}/x" and installed
+// paths such as "{project-root}/_bmad/<module>/workflows/..." whose source is
+// under wfDir.
+func (def *workflowDef) localFile(ref, wfDir, methodDir string) (string, bool) {
+	ref = strings.TrimSpace(ref)
+	var path string
+	switch {
+	case ref == "":
+		return "", false
+	case strings.HasPrefix(ref, "{installed_path}/"):
+		path = filepath.Join(wfDir, strings.TrimPrefix(ref, "{installed_path}/"))
+	default:
+		rel := strings.TrimPrefix(ref, "{project-root}/")
+		for _, p := range []string{"_bmad/", "bmad/"} {
+			if strings.HasPrefix(rel, p) {
+				rel = strings.TrimPrefix(rel, p)
+				break
+			}
+		}
+		path = filepath.Join(methodDir, "src", filepath.FromSlash(rel))
+	}
+	inside, err := filepath.Rel(wfDir, path)
+	if err != nil || strings.HasPrefix(inside, "..") || !fileExists(path) {
+		return "", false
+	}
+	return path, true
+}
+
+// resolve substitutes {installed_path} with the generated skill directory and
+// {config_source} with the config file the workflow declares.
+func (def *workflowDef) resolve(s, skillDir string) string {
+	s = strings.ReplaceAll(s, "{installed_path}", skillDir)
+	if def.ConfigSource != "" {
+		s = strings.ReplaceAll(s, "{config_source}", def.ConfigSource)
+	}
+	return s
+}
+
+// vars returns base plus the workflow variables read from the module config
+// ("{config_source}:key" with a known key), which are then substituted too.
+func (def *workflowDef) vars(base bmadVars) bmadVars {
+	out := make(bmadVars, len(base))
+	for k, v := range base {
+		out[k] = v
+	}
+	for _, v := range append(append([]workflowVar{}, def.Config...), def.Variables...) {
+		key, ok := strings.CutPrefix(v.Value, "{config_source}:")
+		if val, known := base[key]; ok && known && !runtimePlaceholders[v.Key] {
+			out[v.Key] = val
+		}
+	}
+	return out
+}
+
+// declared returns the names of the workflow variables, which the
+// instructions may use as placeholders.
+func (def *workflowDef) declared() map[string]bool {
+	out := make(map[string]bool)
+	for _, v := range append(append([]workflowVar{}, def.Config...), def.Variables...) {
+		out[v.Key] = true
+	}
+	return out
+}
+
+// yamlWorkflow is a workflow.yaml with its declared files loaded.
+type yamlWorkflow struct {
+	def          *workflowDef
+	vars         bmadVars // module and workflow variables, substituted in files
+	instructions *namedContent
+	validation   *namedContent
+	templates    []namedContent
+	data         []namedContent
+	files        map[string]string // every local file the skill references, by path relative to the workflow dir
+}
+
+// loadYAMLWorkflow parses the workflow.yaml at path and reads the files it
+// declares, substituting vars in them. References that do not resolve to a
+// file of the workflow are reported as warnings.
+func loadYAMLWorkflow(path, methodDir, rel string, vars bmadVars, report *conversionReport) (*yamlWorkflow, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	def, err := parseWorkflowYAML(string(content))
+	if err != nil {
+		return nil, err
+	}
+	wfDir := filepath.Dir(path)
+	wf := &yamlWorkflow{def: def, vars: def.vars(vars), files: make(map[string]string)}
+	srcFile, _ := filepath.Rel(methodDir, path)
+	srcFile = filepath.ToSlash(srcFile)
+
+	load := func(what, ref string) *namedContent {
+		if ref == "" {
+			return nil
+		}
+		p, ok := def.localFile(ref, wfDir, methodDir)
+		if !ok {
+			if strings.HasPrefix(ref, "{installed_path}/") {
+				report.warn(diagWorkflowFile, srcFile, "workflow %s: %s %s not found", rel, what, ref)
+			}
+			return nil
+		}
+		name, _ := filepath.Rel(wfDir, p)
+		name = filepath.ToSlash(name)
+		if c, seen := wf.files[name]; seen {
+			return &namedContent{name: name, content: c}
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			report.warn(diagIO, srcFile, "workflow %s: read %s: %v", rel, name, err)
+			return nil
+		}
+		wf.files[name] = wf.vars.expand(string(data))
+		return &namedContent{name: name, content: wf.files[name]}
+	}
+
+	wf.instructions = load("instructions", def.Instructions)
+	wf.validation = load("validation", def.Validation)
+	if t := load("template", def.Template); t != nil {
+		wf.templates = append(wf.templates, *t)
+	}
+	for _, ref := range def.WebBundle {
+		before := len(wf.files)
+		f := load("web bundle file", ref)
+		if f == nil || len(wf.files) == before {
+			continue
+		}
+		lower := strings.ToLower(f.name)
+		if strings.Contains(lower, "template") || strings.Contains(lower, "tmpl") {
+			wf.templates = append(wf.templates, *f)
+		} else {
+			wf.data = append(wf.data, *f)
+		}
+	}
+	return wf, nil
+}
+
+// body renders the part of the skill that replaces the raw workflow.yaml:
+// configuration, instructions and validation checklist. With link set, the
+// instructions and checklist are referenced rather than inlined; the files
+// are written next to SKILL.md anyway.
+func (wf *yamlWorkflow) body(skillDir string, link bool) string {
+	def := wf.def
+	var b strings.Builder
+	w := func(f string, a ...any) { fmt.Fprintf(&b, f, a...) }
+
+	w("# %s\n\n", firstNonEmpty(def.Name, "Workflow"))
+	if def.Description != "" {
+		w("%s\n\n", def.Description)
+	}
+
+	configValue := func(v string) string {
+		if key, ok := strings.CutPrefix(v, "{config_source}:"); ok {
+			if val, known := wf.vars[key]; known {
+				return fmt.Sprintf("`%s`", val)
+			}
+			if def.ConfigSource != "" {
+				return fmt.Sprintf("`%s` from `%s`", key, def.ConfigSource)
+			}
+		}
+		return fmt.Sprintf("`%s`", def.resolve(wf.vars.expand(v), skillDir))
+	}
+	if len(def.Config) > 0 || len(def.Variables) > 0 || def.ConfigSource != "" {
+		w("## Configuration\n\n")
+		if def.ConfigSource != "" {
+			w("- **config_source**: `%s`\n", def.ConfigSource)
+		}
+		for _, v := range append(append([]workflowVar{}, def.Config...), def.Variables...) {
+			w("- **%s**: %s\n", v.Key, configValue(v.Value))
+		}
+		w("- **installed_path**: `%s`\n\n", skillDir)
+	}
+
+	section := func(title, intro string, f *namedContent) {
+		if f == nil {
+			return
+		}
+		w("## %s\n\n", title)
+		if link {
+			w("%s `%s/%s`.\n\n", intro, skillDir, f.name)
+			return
+		}
+		w("Source: `%s/%s`\n\n", skillDir, f.name)
+		w("%s\n\n", def.resolve(strings.TrimRight(f.content, "\n"), skillDir))
+	}
+	section("Instructions", "Read and follow", wf.instructions)
+	section("Validation Checklist", "When validating, use the checklist in", wf.validation)
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// --- Skill descriptions ---
+
+// splitFrontmatter separates a leading "---" YAML frontmatter block from the
+// rest of a markdown file. ok is false when there is none.
+func splitFrontmatter(content string) (front, body string, ok bool) {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	if !strings.HasPrefix(content, "---\n") {
+		return "", content, false
+	}
+	rest := content[len("---\n"):]
+	end := strings.Index(rest, "\n---")
+	for end >= 0 {
+		after := rest[end+len("\n---"):]
+		if after == "" || after[0] == '\n' {
+			return rest[:end+1], strings.TrimPrefix(after, "\n"), true
+		}
+		next := strings.Index(after, "\n---")
+		if next < 0 {
+			break
+		}
+		end += len("\n---") + next
+	}
+	return "", content, false
+}
+
+// frontmatterSteps returns the explicit step order declared by a markdown
+// workflow's frontmatter "steps" list, if any.
+func frontmatterSteps(content string) []string {
+	front, _, ok := splitFrontmatter(content)
+	if !ok {
+		return nil
+	}
+	doc, err := parseYAML(front)
+	if err != nil {
+		return nil
+	}
+	return yamlStrings(yamlMap(doc)["steps"])
+}
+
+var taskNameAttr = regexp.MustCompile(`<task\b[^>]*\bname="([^"]+)"`)
+
+// skillDescription extracts a description from a workflow or task file: the
+// frontmatter description, else the first markdown heading, else the name of
+// an XML <task>. It returns "" when none is found.
+func skillDescription(content string) string {
+	front, body, ok := splitFrontmatter(content)
+	if ok {
+		if doc, err := parseYAML(front); err == nil {
+			if d := yamlString(yamlMap(doc)["description"]); strings.TrimSpace(d) != "" {
+				return oneLine(d)
+			}
+		}
+	}
+	for _, line := range strings.Split(body, "\n") {
+		t := strings.TrimSpace(line)
+		if strings.HasPrefix(t, "# ") {
+			return oneLine(strings.TrimPrefix(t, "# "))
+		}
+	}
+	if m := taskNameAttr.FindStringSubmatch(body); m != nil {
+		return oneLine(m[1])
+	}
+	return ""
+}
+
+// describeSkill returns found, clamped to the spec limit, or the generic
+// description when found is empty. Either fallback is flagged in the report.
+func describeSkill(module, kind, slug, found string, report *conversionReport) string {
+	rel := "skills/" + slug + "/SKILL.md"
+	if found == "" {
+		report.warn(diagNoDescription, rel, "no description found, using the generic one")
+		return fmt.Sprintf("BMAD %s %s — auto-generated by bmad2vibe", strings.ToUpper(module), kind)
+	}
+	if n := utf8.RuneCountInString(found); n > maxSkillDescription {
+		found = clampDescription(found, maxSkillDescription)
+		report.warn(diagLongDescription, rel, "description is %d characters, shortened to the limit of %d", n, maxSkillDescription)
+	}
+	return found
+}
+
+// clampDescription cuts s to at most max runes, at a word boundary when
+// there is one, and marks the cut with an ellipsis.
+func clampDescription(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	cut := string(r[:max-1])
+	if i := strings.LastIndexByte(cut, ' '); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,;:") + "…"
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// tableCell makes s safe inside a markdown table cell.
+func tableCell(s string) string {
+	return strings.ReplaceAll(oneLine(s), "|", "\\|")
+}
+
+// yamlQuote renders s as a double-quoted YAML scalar.
+func yamlQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, r)
+		case r == 0x85 || r == 0x2028 || r == 0x2029 || r == 0xfeff:
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
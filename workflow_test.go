@@ -0,0 +1,130 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestDescribeSkill(t *testing.T) {
+	report := &conversionReport{}
+	if got := describeSkill("bmm", "workflow", "bmad-bmm-x", "Plan a sprint", report); got != "Plan a sprint" {
+		t.Errorf("short description changed to %q", got)
+	}
+	if got := describeSkill("bmm", "task", "bmad-bmm-y", "", report); got != "BMAD BMM task — auto-generated by bmad2vibe" {
+		t.Errorf("generic description = %q", got)
+	}
+
+	long := strings.Repeat("héllo wörld, ", 100) // 1300 runes
+	got := describeSkill("bmm", "workflow", "bmad-bmm-z", long, report)
+	if n := utf8.RuneCountInString(got); n > maxSkillDescription {
+		t.Errorf("clamped description is %d runes", n)
+	}
+	if !strings.HasSuffix(got, "wörld, héllo…") || !strings.HasPrefix(long, strings.TrimSuffix(got, "…")) {
+		t.Errorf("not cut at a word boundary: %q", got[len(got)-40:])
+	}
+	if len(report.errors) > 0 || len(report.warnings) != 2 || report.warnings[1].Code != diagLongDescription.ID {
+		t.Errorf("diagnostics: errors %v, warnings %v", report.errors, report.warnings)
+	}
+
+	if got := clampDescription(strings.Repeat("x", 2000), 10); got != "xxxxxxxxx…" {
+		t.Errorf("clamp without spaces = %q", got)
+	}
+}
+
+func TestTableCell(t *testing.T) {
+	if got := tableCell("Plan | build\n  ship"); got != `Plan \| build ship` {
+		t.Errorf("tableCell = %q", got)
+	}
+}
+
+func TestYAMLWorkflowSkill(t *testing.T) {
+	dir := t.TempDir()
+	wf := "method/src/bmm/workflows/4-implementation/dev-story/"
+	writeTree(t, dir, map[string]string{
+		"bundles/bmm/agents/.keep": "",
+		"method/src/core/module.yaml": `user_name:
+  prompt: "What should agents call you?"
+  default: "Ada"
+communication_language:
+  default: "English"
+`,
+		wf + "workflow.yaml": `name: dev-story
+description: "Execute a story by implementing tasks, writing tests and validating."
+author: "BMad"
+
+# Critical variables from config
+config_source: "{project-root}/_bmad/bmm/config.yaml"
+user_name: "{config_source}:user_name"
+communication_language: "{config_source}:communication_language"
+story_file: ""
+
+# Workflow components
+installed_path: "{project-root}/_bmad/bmm/workflows/4-implementation/dev-story"
+instructions: "{installed_path}/instructions.xml"
+validation: "{installed_path}/checklist.md"
+template: false
+standalone: true
+`,
+		wf + "instructions.xml": `<workflow>
+  <critical>Communicate in {communication_language} with {user_name}</critical>
+  <step n="1" goal="Find the story">Read {story_file}, then check it against {installed_path}/checklist.md</step>
+</workflow>
+`,
+		wf + "checklist.md": "# Definition of Done\n\n- [ ] All tasks complete\n- [ ] Tests pass\n",
+	})
+	home := filepath.Join(dir, ".vibe")
+	runTestConversion(t, vibeTarget{}, scopeGlobal, home, "", filepath.Join(dir, "bundles"), filepath.Join(dir, "method"), false)
+
+	skillDir := filepath.Join(home, "skills", "bmad-bmm-4-implementation-dev-story")
+	data, err := os.ReadFile(filepath.Join(skillDir, "SKILL.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	skill := string(data)
+	front, body, _ := splitFrontmatter(skill)
+	if !strings.Contains(front, `description: "Execute a story by implementing tasks, writing tests and validating."`) {
+		t.Errorf("frontmatter:\n%s", front)
+	}
+
+	// The sections replace the raw YAML, in this order.
+	sections := []string{
+		"## Configuration\n\n" +
+			"- **config_source**: `{project-root}/_bmad/bmm/config.yaml`\n" +
+			"- **author**: `BMad`\n" +
+			"- **user_name**: `Ada`\n" +
+			"- **communication_language**: `English`\n" +
+			"- **story_file**: ``\n" +
+			"- **standalone**: `true`\n",
+		"- **installed_path**: `" + skillDir + "`\n",
+		"## Instructions\n\nSource: `" + skillDir + "/instructions.xml`\n\n<workflow>\n" +
+			"  <critical>Communicate in English with Ada</critical>\n" +
+			"  <step n=\"1\" goal=\"Find the story\">Read {story_file}, then check it against " + skillDir + "/checklist.md</step>\n",
+		"## Validation Checklist\n\nSource: `" + skillDir + "/checklist.md`\n\n# Definition of Done\n\n- [ ] All tasks complete\n- [ ] Tests pass\n",
+	}
+	last := -1
+	for _, s := range sections {
+		i := strings.Index(body, s)
+		if i < 0 {
+			t.Errorf("SKILL.md lacks %q:\n%s", s, body)
+			continue
+		}
+		if i < last {
+			t.Errorf("%q out of order:\n%s", s, body)
+		}
+		last = i
+	}
+	for _, raw := range []string{"installed_path: ", "template: false", "author:", "{installed_path}"} {
+		if strings.Contains(body, raw) {
+			t.Errorf("raw workflow.yaml %q left in SKILL.md:\n%s", raw, body)
+		}
+	}
+	// The files the sections cite are installed next to SKILL.md.
+	for _, f := range []string{"instructions.xml", "checklist.md"} {
+		if _, err := os.Stat(filepath.Join(skillDir, f)); err != nil {
+			t.Error(err)
+		}
+	}
+}
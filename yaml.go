@@ -0,0 +1,739 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// --- YAML parser ---
+//
+// A YAML reader for the subset BMAD sources use: block mappings and
+// sequences, flow collections, plain, single- and double-quoted scalars
+// (including multi-line ones), literal and folded block scalars, and
+// comments. Anchors, aliases and multiple documents are not supported; tags
+// are ignored. Values decode to map[string]any, []any, string, bool, int64,
+// float64 or nil. Quoted scalars are always strings.
+
+type yamlError struct {
+	Line int
+	Msg  string
+}
+
+func (e *yamlError) Error() string { return fmt.Sprintf("line %d: %s", e.Line, e.Msg) }
+
+type yamlParser struct {
+	lines []string
+	idx   int
+}
+
+// parseYAML parses a single YAML document.
+func parseYAML(src string) (any, error) {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.TrimPrefix(src, "\ufeff")
+	p := &yamlParser{lines: strings.Split(src, "\n")}
+
+	p.skipBlank()
+	if p.idx < len(p.lines) && strings.TrimRight(p.lines[p.idx], " ") == "---" {
+		p.idx++
+	}
+	p.skipBlank()
+	if p.idx >= len(p.lines) {
+		return nil, nil
+	}
+	v, err := p.parseNode(p.indent(p.idx), -1)
+	if err != nil {
+		return nil, err
+	}
+	p.skipBlank()
+	if p.idx < len(p.lines) {
+		if l := strings.TrimRight(p.lines[p.idx], " "); l != "..." && l != "---" {
+			return nil, p.errorf(p.idx, "unexpected content %q", strings.TrimSpace(l))
+		}
+	}
+	return v, nil
+}
+
+func (p *yamlParser) errorf(idx int, format string, a ...any) error {
+	return &yamlError{Line: idx + 1, Msg: fmt.Sprintf(format, a...)}
+}
+
+// blank reports whether line i is empty or a comment.
+func (p *yamlParser) blank(i int) bool {
+	t := strings.TrimLeft(p.lines[i], " \t")
+	return t == "" || t[0] == '#'
+}
+
+func (p *yamlParser) skipBlank() {
+	for p.idx < len(p.lines) && p.blank(p.idx) {
+		p.idx++
+	}
+}
+
+func (p *yamlParser) indent(i int) int {
+	return len(p.lines[i]) - len(strings.TrimLeft(p.lines[i], " "))
+}
+
+// parseNode parses the block node starting at the current line, whose
+// indentation is ind. parent is the indentation of the enclosing node.
+func (p *yamlParser) parseNode(ind, parent int) (any, error) {
+	line := p.lines[p.idx]
+	if strings.HasPrefix(strings.TrimLeft(line, " "), "\t") {
+		return nil, p.errorf(p.idx, "tabs are not allowed in indentation")
+	}
+	content := line[ind:]
+	switch {
+	case isSeqItem(content):
+		return p.parseSeq(ind)
+	case mappingColon(content) >= 0:
+		return p.parseMap(ind)
+	default:
+		return p.parseInline(content, parent)
+	}
+}
+
+func isSeqItem(s string) bool {
+	return s == "-" || strings.HasPrefix(s, "- ")
+}
+
+func (p *yamlParser) parseMap(ind int) (map[string]any, error) {
+	m := make(map[string]any)
+	for {
+		p.skipBlank()
+		if p.idx >= len(p.lines) || p.indent(p.idx) != ind {
+			if p.idx < len(p.lines) && p.indent(p.idx) > ind {
+				return nil, p.errorf(p.idx, "unexpected indentation")
+			}
+			return m, nil
+		}
+		content := p.lines[p.idx][ind:]
+		if strings.HasPrefix(content, "\t") {
+			return nil, p.errorf(p.idx, "tabs are not allowed in indentation")
+		}
+		if content == "---" || content == "..." {
+			return m, nil
+		}
+		colon := mappingColon(content)
+		if colon < 0 || isSeqItem(content) {
+			return nil, p.errorf(p.idx, "expected a mapping key, found %q", strings.TrimSpace(content))
+		}
+		key, err := p.parseKey(content[:colon])
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := m[key]; dup {
+			return nil, p.errorf(p.idx, "duplicate key %q", key)
+		}
+		rest := strings.TrimLeft(content[colon+1:], " ")
+		v, err := p.parseValue(rest, ind, true)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+}
+
+func (p *yamlParser) parseSeq(ind int) ([]any, error) {
+	list := []any{}
+	for {
+		p.skipBlank()
+		if p.idx < len(p.lines) && p.indent(p.idx) == ind && strings.HasPrefix(p.lines[p.idx][ind:], "\t") {
+			return nil, p.errorf(p.idx, "tabs are not allowed in indentation")
+		}
+		if p.idx >= len(p.lines) || p.indent(p.idx) != ind || !isSeqItem(p.lines[p.idx][ind:]) {
+			if p.idx < len(p.lines) && p.indent(p.idx) > ind {
+				return nil, p.errorf(p.idx, "unexpected indentation")
+			}
+			return list, nil
+		}
+		content := p.lines[p.idx][ind:]
+		rest := strings.TrimLeft(strings.TrimPrefix(content, "-"), " ")
+		if rest != "" && rest[0] != '#' && (isSeqItem(rest) || mappingColon(rest) >= 0 && rest[0] != '[' && rest[0] != '{') {
+			// "- key: value" or "- - x": the item is a block node starting at
+			// the column after the dash.
+			col := len(p.lines[p.idx]) - len(rest)
+			p.lines[p.idx] = strings.Repeat(" ", col) + rest
+			v, err := p.parseNode(col, ind)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+			continue
+		}
+		v, err := p.parseValue(rest, ind, false)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+}
+
+// parseValue parses what follows "key:" or "- " on the current line. ind is
+// the indentation of the owning mapping or sequence. In a mapping, a nested
+// sequence may sit at the same indentation as the key.
+func (p *yamlParser) parseValue(rest string, ind int, inMap bool) (any, error) {
+	rest = stripTag(rest)
+	if rest == "" || rest[0] == '#' {
+		p.idx++
+		p.skipBlank()
+		if p.idx >= len(p.lines) {
+			return nil, nil
+		}
+		child := p.indent(p.idx)
+		if child > ind || inMap && child == ind && isSeqItem(p.lines[p.idx][child:]) {
+			return p.parseNode(child, ind)
+		}
+		return nil, nil
+	}
+	if rest[0] == '&' || rest[0] == '*' {
+		return nil, p.errorf(p.idx, "anchors and aliases are not supported")
+	}
+	if rest[0] == '|' || rest[0] == '>' {
+		return p.parseBlockScalar(rest, ind)
+	}
+	return p.parseInline(rest, ind)
+}
+
+// stripTag drops a leading tag such as "!!str".
+func stripTag(s string) string {
+	if !strings.HasPrefix(s, "!") {
+		return s
+	}
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		return strings.TrimLeft(s[i:], " ")
+	}
+	return ""
+}
+
+// parseInline parses a scalar or flow collection that starts on the current
+// line and may continue on following lines indented deeper than parent.
+func (p *yamlParser) parseInline(text string, parent int) (any, error) {
+	start := p.idx
+	// continuation returns the next line belonging to the value, if any.
+	continuation := func() (string, bool) {
+		j := p.idx + 1
+		for j < len(p.lines) && strings.TrimSpace(p.lines[j]) == "" {
+			j++
+		}
+		if j >= len(p.lines) || p.indent(j) <= parent {
+			return "", false
+		}
+		return p.lines[j], true
+	}
+
+	switch text[0] {
+	case '"', '\'':
+		q := text[0]
+		raw := text
+		for closingQuote(raw, q) < 0 {
+			if p.idx+1 >= len(p.lines) {
+				return nil, p.errorf(start, "unterminated quoted string")
+			}
+			p.idx++
+			raw += "\n" + p.lines[p.idx]
+		}
+		end := closingQuote(raw, q)
+		if tail := strings.TrimSpace(raw[end+1:]); tail != "" && tail[0] != '#' {
+			return nil, p.errorf(p.idx, "unexpected text after quoted string: %q", tail)
+		}
+		p.idx++
+		s, err := unquoteYAML(raw[:end+1])
+		if err != nil {
+			return nil, p.errorf(start, "%v", err)
+		}
+		return s, nil
+
+	case '[', '{':
+		raw := text
+		for !flowBalanced(raw) {
+			if p.idx+1 >= len(p.lines) {
+				return nil, p.errorf(start, "unterminated flow collection")
+			}
+			p.idx++
+			raw += "\n" + p.lines[p.idx]
+		}
+		p.idx++
+		fp := &flowParser{src: raw}
+		v, err := fp.parse()
+		if err != nil {
+			return nil, p.errorf(start, "%v", err)
+		}
+		if tail := strings.TrimSpace(fp.src[fp.pos:]); tail != "" && tail[0] != '#' {
+			return nil, p.errorf(start, "unexpected text after flow collection: %q", tail)
+		}
+		return v, nil
+	}
+
+	// Plain scalar, folded across more-indented continuation lines.
+	parts := []string{stripComment(text)}
+	for {
+		next, ok := continuation()
+		if !ok {
+			break
+		}
+		t := strings.TrimSpace(next)
+		if t[0] == '#' {
+			break
+		}
+		// Blank lines between continuation lines become newlines.
+		for p.idx+1 < len(p.lines) && strings.TrimSpace(p.lines[p.idx+1]) == "" {
+			parts = append(parts, "\n")
+			p.idx++
+		}
+		p.idx++
+		parts = append(parts, stripComment(t))
+	}
+	p.idx++
+	var b strings.Builder
+	for i, s := range parts {
+		if i > 0 && s != "\n" && parts[i-1] != "\n" {
+			b.WriteByte(' ')
+		}
+		b.WriteString(s)
+	}
+	return plainScalar(strings.TrimSpace(b.String())), nil
+}
+
+// parseBlockScalar parses a "|" or ">" scalar whose header is on the
+// current line; ind is the indentation of the owning node.
+func (p *yamlParser) parseBlockScalar(header string, ind int) (string, error) {
+	header = stripComment(header)
+	folded := header[0] == '>'
+	chomp := byte(0)
+	explicit := 0
+	for _, c := range header[1:] {
+		switch {
+		case c == '-' || c == '+':
+			chomp = byte(c)
+		case c >= '1' && c <= '9':
+			explicit = int(c - '0')
+		case c == ' ':
+		default:
+			return "", p.errorf(p.idx, "invalid block scalar header %q", header)
+		}
+	}
+	p.idx++
+
+	contentIndent := -1
+	if explicit > 0 {
+		contentIndent = ind + explicit
+		if ind < 0 {
+			contentIndent = explicit
+		}
+	}
+	var lines []string
+	for p.idx < len(p.lines) {
+		line := p.lines[p.idx]
+		if strings.TrimSpace(line) == "" {
+			lines = append(lines, "")
+			p.idx++
+			continue
+		}
+		n := p.indent(p.idx)
+		if contentIndent < 0 {
+			if n <= ind {
+				break
+			}
+			contentIndent = n
+		}
+		if n < contentIndent {
+			break
+		}
+		lines = append(lines, line[contentIndent:])
+		p.idx++
+	}
+
+	// Trailing blank lines belong to the scalar only for chomping purposes.
+	trailing := 0
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+	// Leave those blank lines for the caller if they precede a dedent.
+	var b strings.Builder
+	if folded {
+		// A line break between two text lines folds into a space; with
+		// blank lines in between, only the blank lines remain. Breaks around
+		// more-indented lines are kept.
+		blanks, prev := 0, ""
+		for i, l := range lines {
+			if l == "" {
+				blanks++
+				continue
+			}
+			switch {
+			case i == blanks: // first text line
+				b.WriteString(strings.Repeat("\n", blanks))
+			case strings.HasPrefix(l, " ") || strings.HasPrefix(prev, " "):
+				b.WriteString(strings.Repeat("\n", blanks+1))
+			case blanks == 0:
+				b.WriteByte(' ')
+			default:
+				b.WriteString(strings.Repeat("\n", blanks))
+			}
+			b.WriteString(l)
+			blanks, prev = 0, l
+		}
+	} else {
+		b.WriteString(strings.Join(lines, "\n"))
+	}
+	s := b.String()
+	switch chomp {
+	case '-':
+	case '+':
+		if len(lines) > 0 {
+			s += "\n"
+		}
+		s += strings.Repeat("\n", trailing)
+	default:
+		if len(lines) > 0 {
+			s += "\n"
+		}
+	}
+	return s, nil
+}
+
+// parseKey unquotes a mapping key.
+func (p *yamlParser) parseKey(raw string) (string, error) {
+	k := strings.TrimSpace(raw)
+	if k == "" {
+		return "", p.errorf(p.idx, "empty mapping key")
+	}
+	if k[0] == '"' || k[0] == '\'' {
+		s, err := unquoteYAML(k)
+		if err != nil {
+			return "", p.errorf(p.idx, "%v", err)
+		}
+		return s, nil
+	}
+	return k, nil
+}
+
+// mappingColon returns the index of the ":" that separates a mapping key
+// from its value in s, or -1.
+func mappingColon(s string) int {
+	if s == "" || s[0] == '#' || s[0] == '[' || s[0] == '{' {
+		return -1
+	}
+	if s[0] == '"' || s[0] == '\'' {
+		end := closingQuote(s, s[0])
+		if end < 0 {
+			return -1
+		}
+		rest := strings.TrimLeft(s[end+1:], " ")
+		if strings.HasPrefix(rest, ":") && (len(rest) == 1 || rest[1] == ' ') {
+			return len(s) - len(rest)
+		}
+		return -1
+	}
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case ':':
+			if i+1 == len(s) || s[i+1] == ' ' {
+				return i
+			}
+		case '#':
+			if i > 0 && s[i-1] == ' ' {
+				return -1
+			}
+		}
+	}
+	return -1
+}
+
+// closingQuote returns the index of the quote closing the string that opens
+// s[0], or -1.
+func closingQuote(s string, q byte) int {
+	for i := 1; i < len(s); i++ {
+		switch {
+		case q == '"' && s[i] == '\\':
+			i++
+		case s[i] == q:
+			if q == '\'' && i+1 < len(s) && s[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// unquoteYAML decodes a single- or double-quoted scalar, folding line breaks.
+func unquoteYAML(raw string) (string, error) {
+	q := raw[0]
+	body := raw[1 : len(raw)-1]
+
+	// Fold: a single line break becomes a space, n empty lines n newlines.
+	lines := strings.Split(body, "\n")
+	var b strings.Builder
+	for i, l := range lines {
+		if i > 0 {
+			l = strings.TrimLeft(l, " \t")
+		}
+		if i < len(lines)-1 && !(q == '"' && strings.HasSuffix(l, "\\") && !strings.HasSuffix(l, "\\\\")) {
+			l = strings.TrimRight(l, " \t")
+		}
+		switch {
+		case i == 0:
+		case l == "" && i < len(lines)-1:
+			b.WriteByte('\n')
+			continue
+		case strings.HasSuffix(b.String(), "\n") || q == '"' && strings.HasSuffix(b.String(), "\\"):
+		default:
+			b.WriteByte(' ')
+		}
+		b.WriteString(l)
+	}
+	s := b.String()
+
+	if q == '\'' {
+		return strings.ReplaceAll(s, "''", "'"), nil
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' {
+			out.WriteByte(c)
+			continue
+		}
+		i++
+		if i >= len(s) {
+			return "", fmt.Errorf("trailing backslash in string")
+		}
+		switch e := s[i]; e {
+		case '0':
+			out.WriteByte(0)
+		case 'a':
+			out.WriteByte('\a')
+		case 'b':
+			out.WriteByte('\b')
+		case 't', '\t':
+			out.WriteByte('\t')
+		case 'n':
+			out.WriteByte('\n')
+		case 'v':
+			out.WriteByte('\v')
+		case 'f':
+			out.WriteByte('\f')
+		case 'r':
+			out.WriteByte('\r')
+		case 'e':
+			out.WriteByte(0x1b)
+		case ' ', '"', '/', '\\':
+			out.WriteByte(e)
+		case 'N':
+			out.WriteString("\u0085")
+		case '_':
+			out.WriteString(" ")
+		case 'x', 'u', 'U':
+			n := map[byte]int{'x': 2, 'u': 4, 'U': 8}[e]
+			if i+n >= len(s) {
+				return "", fmt.Errorf("short \\%c escape", e)
+			}
+			code, err := strconv.ParseUint(s[i+1:i+1+n], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("invalid \\%c escape", e)
+			}
+			out.WriteRune(rune(code))
+			i += n
+		default:
+			return "", fmt.Errorf("invalid escape \\%c", e)
+		}
+	}
+	return out.String(), nil
+}
+
+// stripComment removes a trailing " # comment" from a plain scalar.
+func stripComment(s string) string {
+	if strings.HasPrefix(s, "#") {
+		return ""
+	}
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimRight(s, " \t")
+}
+
+// plainScalar resolves the type of an unquoted scalar.
+func plainScalar(s string) any {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	if strings.ContainsAny(s, ".eE") && strings.IndexFunc(s, func(r rune) bool { return !strings.ContainsRune("0123456789.eE+-", r) }) < 0 {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+	return s
+}
+
+func flowBalanced(s string) bool {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\'':
+			end := closingQuote(s[i:], c)
+			if end < 0 {
+				return false
+			}
+			i += end
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+			if depth == 0 {
+				return true
+			}
+		}
+	}
+	return depth == 0
+}
+
+// flowParser parses flow collections: [a, b] and {k: v}.
+type flowParser struct {
+	src string
+	pos int
+}
+
+func (f *flowParser) skip() {
+	for f.pos < len(f.src) && strings.IndexByte(" \t\n", f.src[f.pos]) >= 0 {
+		f.pos++
+	}
+}
+
+func (f *flowParser) parse() (any, error) {
+	f.skip()
+	if f.pos >= len(f.src) {
+		return nil, fmt.Errorf("unexpected end of flow collection")
+	}
+	switch c := f.src[f.pos]; c {
+	case '[':
+		f.pos++
+		list := []any{}
+		for {
+			f.skip()
+			if f.pos < len(f.src) && f.src[f.pos] == ']' {
+				f.pos++
+				return list, nil
+			}
+			v, err := f.parse()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+			f.skip()
+			if f.pos < len(f.src) && f.src[f.pos] == ',' {
+				f.pos++
+			} else if f.pos >= len(f.src) || f.src[f.pos] != ']' {
+				return nil, fmt.Errorf("expected ',' or ']' in flow sequence")
+			}
+		}
+	case '{':
+		f.pos++
+		m := make(map[string]any)
+		for {
+			f.skip()
+			if f.pos < len(f.src) && f.src[f.pos] == '}' {
+				f.pos++
+				return m, nil
+			}
+			k, err := f.parse()
+			if err != nil {
+				return nil, err
+			}
+			f.skip()
+			var v any
+			if f.pos < len(f.src) && f.src[f.pos] == ':' {
+				f.pos++
+				if v, err = f.parse(); err != nil {
+					return nil, err
+				}
+			}
+			m[yamlString(k)] = v
+			f.skip()
+			if f.pos < len(f.src) && f.src[f.pos] == ',' {
+				f.pos++
+			} else if f.pos >= len(f.src) || f.src[f.pos] != '}' {
+				return nil, fmt.Errorf("expected ',' or '}' in flow mapping")
+			}
+		}
+	case '"', '\'':
+		end := closingQuote(f.src[f.pos:], c)
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated quoted string")
+		}
+		s, err := unquoteYAML(f.src[f.pos : f.pos+end+1])
+		f.pos += end + 1
+		return s, err
+	default:
+		start := f.pos
+		for f.pos < len(f.src) {
+			c := f.src[f.pos]
+			if c == ',' || c == ']' || c == '}' || c == ':' && (f.pos+1 == len(f.src) || strings.IndexByte(" \n,]}", f.src[f.pos+1]) >= 0) {
+				break
+			}
+			f.pos++
+		}
+		return plainScalar(strings.Join(strings.Fields(f.src[start:f.pos]), " ")), nil
+	}
+}
+
+// --- YAML value helpers ---
+
+// yamlString formats a scalar value as a string; collections yield "".
+func yamlString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return ""
+}
+
+func yamlMap(v any) map[string]any {
+	m, _ := v.(map[string]any)
+	return m
+}
+
+func yamlList(v any) []any {
+	l, _ := v.([]any)
+	return l
+}
+
+// yamlStrings returns a list of scalars (or a single scalar) as strings.
+func yamlStrings(v any) []string {
+	switch v := v.(type) {
+	case []any:
+		var out []string
+		for _, e := range v {
+			if s := yamlString(e); s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	case nil:
+		return nil
+	default:
+		if s := yamlString(v); s != "" {
+			return []string{s}
+		}
+	}
+	return nil
+}
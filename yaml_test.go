@@ -0,0 +1,146 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want any
+	}{
+		{"empty", "", nil},
+		{"document markers", "---\na: 1\n...\n", map[string]any{"a": int64(1)}},
+		{"plain scalars", `s: hello world
+i: 42
+f: 1.5
+t: true
+n: null
+tilde: ~
+ver: 1.0.0
+url: http://example.com:8080/x
+`, map[string]any{"s": "hello world", "i": int64(42), "f": 1.5, "t": true, "n": nil, "tilde": nil, "ver": "1.0.0", "url": "http://example.com:8080/x"}},
+		{"nested blocks", `agent:
+  metadata:
+    name: John
+  menu:
+    - trigger: create-prd
+      workflow: "{project-root}/_bmad/bmm/workflows/prd/workflow.md"
+    - trigger: exit
+  tags:
+  - a
+  - b
+`, map[string]any{"agent": map[string]any{
+			"metadata": map[string]any{"name": "John"},
+			"menu": []any{
+				map[string]any{"trigger": "create-prd", "workflow": "{project-root}/_bmad/bmm/workflows/prd/workflow.md"},
+				map[string]any{"trigger": "exit"},
+			},
+			"tags": []any{"a", "b"},
+		}}},
+		{"nested sequences", "- - a\n  - b\n- c\n", []any{[]any{"a", "b"}, "c"}},
+
+		{"literal block", "a: |\n  line 1\n    indented\n\n  line 3\nb: x\n", map[string]any{"a": "line 1\n  indented\n\nline 3\n", "b": "x"}},
+		{"literal strip", "a: |-\n  text\n\n", map[string]any{"a": "text"}},
+		{"literal keep", "a: |+\n  text\n\n\nb: x\n", map[string]any{"a": "text\n\n\n", "b": "x"}},
+		{"folded block", "a: >\n  one\n  two\n\n  three\nb: x\n", map[string]any{"a": "one two\nthree\n", "b": "x"}},
+		{"folded keeps more-indented lines", "a: >\n  one\n    code\n  two\n", map[string]any{"a": "one\n  code\ntwo\n"}},
+		{"folded leading blank lines", "a: >\n\n  one\n  two\n", map[string]any{"a": "\none two\n"}},
+		{"literal keeps tabs in content", "a: |\n  \tcode\n", map[string]any{"a": "\tcode\n"}},
+		{"folded strip", "a: >-\n  one\n  two\n", map[string]any{"a": "one two"}},
+		{"explicit indentation", "a: |2\n    two extra\n  base\n", map[string]any{"a": "  two extra\nbase\n"}},
+		{"block in sequence", "- |\n  text\n- x\n", []any{"text\n", "x"}},
+
+		{"flow sequence", "a: [read_file, 'grep', \"bash\", 3]\n", map[string]any{"a": []any{"read_file", "grep", "bash", int64(3)}}},
+		{"flow mapping", "a: {x: 1, y: [a, b], z: {w: ok}}\n", map[string]any{"a": map[string]any{"x": int64(1), "y": []any{"a", "b"}, "z": map[string]any{"w": "ok"}}}},
+		{"multi-line flow", "a: [\n  one,\n  two,\n]\n", map[string]any{"a": []any{"one", "two"}}},
+		{"empty flow", "a: []\nb: {}\n", map[string]any{"a": []any{}, "b": map[string]any{}}},
+
+		{"single quotes", `a: 'it''s # not a comment'`, map[string]any{"a": "it's # not a comment"}},
+		{"double quote escapes", `a: "tab\there \"q\" \\ \u00e9 \x41"`, map[string]any{"a": "tab\there \"q\" \\ é A"}},
+		{"quoted numbers stay strings", `a: "42"
+b: 'true'
+`, map[string]any{"a": "42", "b": "true"}},
+		{"multi-line quoted", "a: \"one\n  two\"\n", map[string]any{"a": "one two"}},
+		{"quoted key", `"a: b": 1`, map[string]any{"a: b": int64(1)}},
+
+		{"comments", `# header
+a: 1 # trailing
+# between
+b: "# kept" # dropped
+c: x#y
+`, map[string]any{"a": int64(1), "b": "# kept", "c": "x#y"}},
+		{"crlf and bom", "\ufeffa: 1\r\nb: two\r\n", map[string]any{"a": int64(1), "b": "two"}},
+		{"tags ignored", "a: !custom value\nb: !!map\n  c: 1\n", map[string]any{"a": "value", "b": map[string]any{"c": int64(1)}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseYAML(tt.src)
+			if err != nil {
+				t.Fatalf("parseYAML: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseYAML =\n%#v\nwant\n%#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		line int
+		msg  string
+	}{
+		{"tab indentation", "a:\n\tb: 1\n", 2, "tabs"},
+		{"tab in sequence", "-  a\n\t- b\n", 2, "tabs"},
+		{"duplicate key", "a: 1\nb: 2\na: 3\n", 3, "duplicate key"},
+		{"unterminated quote", "a: 1\nb: \"open\n", 2, "unterminated quoted string"},
+		{"unterminated flow", "a: 1\n\nb: [x, y\n", 3, "unterminated flow collection"},
+		{"text after quote", "a: 'x' y\n", 1, "unexpected text after quoted string"},
+		{"bad indentation", "a:\n    b: 1\n  c: 2\n", 3, "indentation"},
+		{"not a key", "a: 1\njust text\n", 2, "expected a mapping key"},
+		{"alias", "a: &x 1\nb: *x\n", 1, "anchors and aliases"},
+		{"bad block header", "a: |x\n  text\n", 1, "invalid block scalar header"},
+		{"trailing content", "- a\nb: 1\n", 2, "unexpected content"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseYAML(tt.src)
+			ye, ok := err.(*yamlError)
+			if !ok {
+				t.Fatalf("parseYAML(%q) error = %v, want a *yamlError", tt.src, err)
+			}
+			if ye.Line != tt.line || !strings.Contains(ye.Msg, tt.msg) {
+				t.Errorf("error = %v, want line %d containing %q", ye, tt.line, tt.msg)
+			}
+		})
+	}
+}
+
+func TestYAMLHelpers(t *testing.T) {
+	doc, err := parseYAML("n: 3\nb: false\nl: [a, 2]\ns: x\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := yamlMap(doc)
+	if got := yamlString(m["n"]); got != "3" {
+		t.Errorf("yamlString(int) = %q", got)
+	}
+	if got := yamlString(m["b"]); got != "false" {
+		t.Errorf("yamlString(bool) = %q", got)
+	}
+	if got := yamlStrings(m["l"]); !reflect.DeepEqual(got, []string{"a", "2"}) {
+		t.Errorf("yamlStrings(list) = %q", got)
+	}
+	if got := yamlStrings(m["s"]); !reflect.DeepEqual(got, []string{"x"}) {
+		t.Errorf("yamlStrings(scalar) = %q", got)
+	}
+	if yamlMap(m["s"]) != nil || yamlList(m["s"]) != nil {
+		t.Error("yamlMap/yamlList of a scalar should be nil")
+	}
+}